@@ -2,15 +2,29 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/abuishgair/astra/internal/assess"
+	"github.com/abuishgair/astra/internal/condense"
 	graph "github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/importer"
 	"github.com/abuishgair/astra/internal/mapper"
 	parse "github.com/abuishgair/astra/internal/parser"
+	"github.com/abuishgair/astra/internal/parser/buildinfo"
+	"github.com/abuishgair/astra/internal/policy"
+	"github.com/abuishgair/astra/internal/query"
+	"github.com/abuishgair/astra/internal/repro"
+	"github.com/abuishgair/astra/internal/risk"
+	"github.com/abuishgair/astra/internal/schema"
+	"github.com/abuishgair/astra/internal/vuln"
 )
 
 func must(err error) {
@@ -31,9 +45,72 @@ func writeJSON(path string, v any) error {
 	return os.WriteFile(path, b, 0o644)
 }
 
+// loadGraph reads an AStRA graph through schema.LoadGraph: older files are
+// upgraded in memory, and files that do not match the schema stop the command.
+func loadGraph(path string) graph.AstraGraph {
+	g, warnings, err := schema.LoadGraph(path)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "[WARN]", w)
+	}
+	must(err)
+	return g
+}
+
+// inputList is a repeatable -i flag.
+type inputList []string
+
+func (l *inputList) String() string { return strings.Join(*l, ",") }
+
+func (l *inputList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// expandInputs resolves files, directories (every *.json inside) and globs
+// into a sorted, de-duplicated list of files.
+func expandInputs(args []string) ([]string, error) {
+	seen := map[string]bool{}
+	var files []string
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	for _, a := range args {
+		if st, err := os.Stat(a); err == nil {
+			if !st.IsDir() {
+				add(a)
+				continue
+			}
+			matches, err := filepath.Glob(filepath.Join(a, "*.json"))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(matches)
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+		matches, err := filepath.Glob(a)
+		if err != nil {
+			return nil, fmt.Errorf("bad input pattern %q: %w", a, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input matches %q", a)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			add(m)
+		}
+	}
+	return files, nil
+}
+
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("usage: astra <parse|map|graph|risk|condense> [flags]")
+		fmt.Println("usage: astra <parse|map|graph|risk|impact|trace|check|slsa|vuln|repro|condense|viz|export|import|migrate|schema> [flags]")
 		os.Exit(2)
 	}
 	sub := os.Args[1]
@@ -58,7 +135,7 @@ func main() {
 		case "slsa": // slsa
 			parser = &parse.SlsaParser{}
 		case "buildinfo": // debian buildinfo logs
-			parser = &parse.BuildinfoParser{}
+			parser = &buildinfo.BuildinfoParser{}
 
 		default:
 			fmt.Fprintf(os.Stderr, "unknown format: %s\n", *format)
@@ -69,25 +146,73 @@ func main() {
 		must(writeJSON(*out, data))
 		fmt.Println("[OK] Parsed ->", *out)
 	case "map":
+		var ins inputList
 		fs := flag.NewFlagSet("map", flag.ExitOnError)
-		in := fs.String("i", "", "input parsed JSON (parser.Mapped)")
+		fs.Var(&ins, "i", "input parsed JSON (parser.Mapped); repeatable, accepts directories and globs")
 		out := fs.String("o", "", "output AStRA graph JSON (typed)")
+		link := fs.String("link", "none", "link artifacts sharing a digest (none|edges|merge)")
+		onConflict := fs.String("on-conflict", "first-wins", "attribute conflicts between inputs (first-wins|last-wins|record)")
+		rulesPath := fs.String("rules", "", "optional mapping rules JSON, keyed by parsed source")
+		trustPath := fs.String("trust", "", "optional trust policy JSON (default: signatures, declared trust, recency)")
 
 		fs.Parse(os.Args[2:])
 
-		if *in == "" || *out == "" {
+		if len(ins) == 0 || *out == "" {
 			fs.Usage()
 			os.Exit(2)
 		}
+		switch mapper.ConflictPolicy(*onConflict) {
+		case mapper.FirstWins, mapper.LastWins, mapper.Record:
+		default:
+			fmt.Fprintf(os.Stderr, "unknown conflict policy: %s\n", *onConflict)
+			os.Exit(1)
+		}
 
-		// Read parsed
-		var parsed parse.Mapped
-		b, err := os.ReadFile(*in)
+		var rules *mapper.Rules
+		if *rulesPath != "" {
+			r, err := mapper.LoadRules(*rulesPath)
+			must(err)
+			rules = r
+		}
+
+		trust := mapper.DefaultTrustPolicy()
+		if *trustPath != "" {
+			t, err := mapper.LoadTrustPolicy(*trustPath)
+			must(err)
+			trust = t
+		}
+
+		files, err := expandInputs(ins)
 		must(err)
-		must(json.Unmarshal(b, &parsed))
 
-		// Convert to typed AStRA graph
-		astra := mapper.ToAstraGraph(parsed)
+		// Read parsed and convert each to typed AStRA graph
+		var graphs []graph.AstraGraph
+		for _, f := range files {
+			parsed, warnings, err := schema.LoadMapped(f)
+			for _, w := range warnings {
+				fmt.Fprintln(os.Stderr, "[WARN]", w)
+			}
+			must(err)
+			graphs = append(graphs, mapper.ToAstraGraphWithRules(parsed, rules))
+		}
+
+		// Deduplicate nodes shared between inputs
+		astra, conflicts := mapper.MergeGraphs(graphs, mapper.ConflictPolicy(*onConflict))
+		for _, c := range conflicts {
+			fmt.Fprintln(os.Stderr, "conflict:", c)
+		}
+
+		// Connect the same content seen by different sources
+		switch mapper.LinkMode(*link) {
+		case mapper.LinkNone, mapper.LinkEdges, mapper.LinkMerge:
+			astra = mapper.LinkByDigest(astra, mapper.LinkMode(*link))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown link mode: %s\n", *link)
+			os.Exit(1)
+		}
+
+		// Derive principal trust from evidence
+		mapper.DeriveTrust(&astra, trust)
 
 		//  validate schema invariants
 		// must(graph.Validate(astra))
@@ -97,70 +222,496 @@ func main() {
 
 		//case "graph":
 	// TODO visual graph with cloned resources
-	/*
-		case "risk":
-				fs := flag.NewFlagSet("risk", flag.ExitOnError)
-				in := fs.String("i", "", "input graph JSON")
-				rep := fs.String("r", "", "output risk report JSON")
-				fromT := fs.String("paths-from", "", "optional source node type for shortest paths")
-				toT := fs.String("paths-to", "", "optional dest node type for shortest paths")
-				fs.Parse(os.Args[2:])
-				if *in == "" || *rep == "" {
-					fs.Usage()
-					os.Exit(2)
-				}
-				var g graph.AstraGraph
-				b, err := os.ReadFile(*in)
+	case "risk":
+		fs := flag.NewFlagSet("risk", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		rep := fs.String("r", "", "output risk report JSON")
+		fromT := fs.String("paths-from", "", "optional source node type for shortest paths")
+		toT := fs.String("paths-to", "", "optional dest node type for shortest paths")
+		spof := fs.Bool("spof", false, "add single-point-of-failure and bus-factor analysis for principals")
+		allArts := fs.Bool("all-artifacts", false, "with -spof, analyze every artifact instead of releases only")
+		depth := fs.Int("dir-depth", 1, "with -spof, directory depth for git file bus factors")
+		score := fs.Bool("score", false, "add weighted per-node and per-artifact risk scores")
+		weights := fs.String("weights", "", "score model JSON (weights, trust risk, sensitive paths); implies -score")
+		anomalies := fs.Bool("anomalies", false, "add findings for new-contributor, dormant-account, signing-key and unusual-hour anomalies")
+		dormant := fs.Int("dormant-months", 6, "with -anomalies, inactivity after which an account counts as dormant")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *rep == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		r := risk.ComputeRiskReport(g, *fromT, *toT)
+		if *spof {
+			s := risk.ComputeSPOF(g, risk.SPOFOptions{AllArtifacts: *allArts, DirDepth: *depth})
+			r.SPOF = &s
+		}
+		if *score || *weights != "" {
+			m := risk.DefaultScoreModel()
+			if *weights != "" {
+				var err error
+				m, err = risk.LoadScoreModel(*weights)
 				must(err)
-				must(json.Unmarshal(b, &g))
-				r := risk.ComputeRiskReport(g, *fromT, *toT)
-				must(writeJSON(*rep, r))
-				fmt.Println("[OK] Risk report ->", *rep)
-
-			case "condense":
-				fs := flag.NewFlagSet("condense", flag.ExitOnError)
-				in := fs.String("i", "", "input graph JSON")
-				out := fs.String("o", "", "output condensed JSON")
-				group := fs.String("group-by", "phase", "phase|type")
-				fs.Parse(os.Args[2:])
-				if *in == "" || *out == "" {
-					fs.Usage()
-					os.Exit(2)
+			}
+			sc := risk.ComputeScores(g, m)
+			r.Scores = &sc
+		}
+		if *anomalies {
+			r.Findings = risk.DetectAnomalies(g, risk.AnomalyOptions{DormantMonths: *dormant})
+			for _, f := range r.Findings {
+				if f.Severity == "high" {
+					fmt.Fprintf(os.Stderr, "[WARN] %s %s at %s: %s\n", f.Kind, f.Principal, f.Step, f.Detail)
 				}
-				var g graph.AstraGraph
-				b, err := os.ReadFile(*in)
-				must(err)
-				must(json.Unmarshal(b, &g))
-				cg := condense.Condense(g, *group)
-				must(writeJSON(*out, cg))
-				fmt.Println("[OK] Condensed ->", *out)
-	*/
+			}
+		}
+		must(writeJSON(*rep, r))
+		fmt.Println("[OK] Risk report ->", *rep)
+
+	case "impact":
+		fs := flag.NewFlagSet("impact", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		node := fs.String("node", "", "compromised node ID (principal, resource, step or artifact)")
+		out := fs.String("o", "impact.json", "output impact report JSON")
+		maxDepth := fs.Int("max-depth", 0, "stop after this many hops (0 = unlimited)")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *node == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		imp, err := query.ComputeImpact(g, *node, *maxDepth)
+		must(err)
+		must(writeJSON(*out, imp))
+		fmt.Printf("[OK] %s affects %d steps, %d artifacts -> %s\n", *node, len(imp.Steps), len(imp.Artifacts), *out)
+
+	case "trace":
+		fs := flag.NewFlagSet("trace", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		art := fs.String("artifact", "", "artifact ID to trace back")
+		out := fs.String("o", "trace.json", "output provenance subgraph JSON")
+		maxDepth := fs.Int("max-depth", 0, "stop after this many hops (0 = unlimited)")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *art == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		t, err := query.ComputeTrace(g, *art, *maxDepth)
+		must(err)
+		for _, l := range t.Chain {
+			fmt.Println(l)
+		}
+		must(writeJSON(*out, t.Graph))
+		fmt.Println("[OK] Provenance subgraph ->", *out)
+
+	case "check":
+		fs := flag.NewFlagSet("check", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		pol := fs.String("p", "", "policy rules JSON")
+		out := fs.String("o", "", "optional output violations JSON")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *pol == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		p, err := policy.Load(*pol)
+		must(err)
+		g := loadGraph(*in)
+		res := policy.Evaluate(p, g)
+		if *out != "" {
+			must(writeJSON(*out, res))
+		}
+		for _, v := range res.Violations {
+			fmt.Printf("%s [%s] %s: %s (failed: %s)\n", strings.ToUpper(v.Severity), v.Rule, v.Node, v.Description, v.Failed)
+		}
+		if res.Errors() > 0 {
+			fmt.Fprintf(os.Stderr, "[FAIL] %d violations (%d errors) of %d rules\n", len(res.Violations), res.Errors(), res.Rules)
+			os.Exit(1)
+		}
+		fmt.Printf("[OK] %d rules, %d checks, %d warnings\n", res.Rules, res.Checked, len(res.Violations))
+
+	case "slsa":
+		fs := flag.NewFlagSet("slsa", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		out := fs.String("o", "slsa.json", "output SLSA assessment JSON")
+		trusted := fs.String("trusted-builders", "", "comma-separated trusted builder ids")
+		all := fs.Bool("all-artifacts", false, "assess every produced artifact, not only releases")
+		fs.Parse(os.Args[2:])
+		if *in == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		opts := assess.Options{AllArtifacts: *all}
+		if *trusted != "" {
+			opts.TrustedBuilders = strings.Split(*trusted, ",")
+		}
+		rep := assess.AssessSLSA(g, opts)
+		for _, a := range rep.Artifacts {
+			unknown := 0
+			for _, r := range a.Requirements {
+				if r.Unknown {
+					unknown++
+				}
+			}
+			fmt.Printf("%s: build L%d, source L%d", a.Artifact, a.BuildLevel, a.SourceLevel)
+			if unknown > 0 {
+				fmt.Printf(" (%d requirements unknown)", unknown)
+			}
+			fmt.Println()
+		}
+		must(writeJSON(*out, rep))
+		fmt.Println("[OK] SLSA assessment ->", *out)
+
+	case "vuln":
+		var dbs inputList
+		fs := flag.NewFlagSet("vuln", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		fs.Var(&dbs, "db", "local OSV or Debian Security Tracker JSON (file or directory); repeatable")
+		out := fs.String("o", "", "output annotated graph JSON")
+		rep := fs.String("r", "vulns.json", "output vulnerability report JSON")
+		release := fs.String("release", "", "Debian release to judge tracker data by (e.g. bookworm); default any")
+		fs.Parse(os.Args[2:])
+		if *in == "" || len(dbs) == 0 || *out == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		db := vuln.NewDB()
+		for _, p := range dbs {
+			must(db.Load(p))
+		}
+		r := vuln.Annotate(&g, db, vuln.Options{Release: *release})
+		must(writeJSON(*out, g))
+		must(writeJSON(*rep, r))
+		fmt.Printf("[OK] %d of %d packages vulnerable (%d advisories), %d artifacts built with them -> %s, %s\n",
+			len(r.Vulnerable), r.Checked, len(r.Matches), len(r.Tainted), *out, *rep)
+
+	case "repro":
+		fs := flag.NewFlagSet("repro", flag.ExitOnError)
+		out := fs.String("o", "repro.json", "output comparison report JSON")
+		gout := fs.String("g", "", "optional output graph JSON of both builds")
+		allDeps := fs.Bool("all-deps", false, "with -g, include build dependencies that match too")
+		keyringPath := fs.String("keyring", "", "optional OpenPGP keyring to verify .buildinfo signatures (e.g. debian-keyring.gpg)")
+		fs.Usage = func() {
+			fmt.Fprintln(os.Stderr, "usage: astra repro [flags] a.buildinfo b.buildinfo")
+			fs.PrintDefaults()
+		}
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		var keys *crypto.KeyRing
+		if *keyringPath != "" {
+			k, err := buildinfo.LoadKeyring(*keyringPath)
+			must(err)
+			keys = k
+		}
+		var builds []repro.Build
+		for _, p := range fs.Args() {
+			g, err := buildinfo.ParseGraph(p, keys)
+			must(err)
+			b, err := repro.FromGraph(p, g)
+			must(err)
+			builds = append(builds, b)
+		}
+		rep := repro.Compare(builds[0], builds[1])
+		for _, o := range rep.Outputs {
+			fmt.Printf("%-7s %s\n", o.Status, o.Name)
+		}
+		for _, e := range rep.Explanations {
+			fmt.Println("  -", e)
+		}
+		must(writeJSON(*out, rep))
+		if *gout != "" {
+			must(writeJSON(*gout, repro.Graph(builds[0], builds[1], rep, *allDeps)))
+		}
+		if !rep.Reproducible {
+			fmt.Fprintln(os.Stderr, "[FAIL] outputs differ ->", *out)
+			os.Exit(1)
+		}
+		fmt.Println("[OK] Reproducible ->", *out)
+
+	case "condense":
+		fs := flag.NewFlagSet("condense", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON")
+		out := fs.String("o", "", "output condensed JSON")
+		group := fs.String("group-by", "phase", "phase|type|session|day|week|release|scc|dir|package")
+		gap := fs.Duration("session-gap", 0, "with -group-by session, split sessions at gaps longer than this (e.g. 8h)")
+		dirDepth := fs.Int("dir-depth", 1, "with -group-by dir, directory segments to keep")
+		expand := fs.String("expand", "", "write the original subgraph of this super-node to -o instead")
+		acyclic := fs.Bool("acyclic", false, "write the graph with every cycle merged into one node (a DAG for astra risk) instead")
+		kcore := fs.Int("kcore", -1, "write the k-core subgraph instead (0 = maximum core)")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *out == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g := loadGraph(*in)
+		if *acyclic {
+			dag, comps := condense.Acyclic(g)
+			must(writeJSON(*out, dag))
+			fmt.Printf("[OK] Merged %d cycles -> %s\n", len(comps), *out)
+			break
+		}
+		if *kcore >= 0 {
+			sub, k := condense.KCore(g, *kcore)
+			must(writeJSON(*out, sub))
+			fmt.Printf("[OK] %d-core: %d nodes -> %s\n", k, len(sub.Artifacts)+len(sub.Steps)+len(sub.Principals)+len(sub.Resources), *out)
+			break
+		}
+		cg, err := condense.CondenseWith(g, *group, condense.Options{SessionGap: *gap, DirDepth: *dirDepth})
+		must(err)
+		if *expand != "" {
+			sub, err := condense.Expand(cg, g, *expand)
+			must(err)
+			must(writeJSON(*out, sub))
+			fmt.Printf("[OK] Expanded %s -> %s\n", *expand, *out)
+			break
+		}
+		must(writeJSON(*out, cg))
+		fmt.Printf("[OK] Condensed %d nodes into %d -> %s\n", len(g.Artifacts)+len(g.Steps)+len(g.Principals)+len(g.Resources), len(cg.Nodes), *out)
+
 	case "viz":
 		fs := flag.NewFlagSet("viz", flag.ExitOnError)
 		in := fs.String("i", "", "input graph JSON")
-		out := fs.String("o", "graph.dot", "output DOT file")
+		out := fs.String("o", "", "output file (default graph.dot, or graph.html with -format html)")
+		format := fs.String("format", "dot", "output format: dot|html")
+		rankdir := fs.String("rankdir", "", "layout direction: TB|LR|BT|RL")
+		cluster := fs.String("cluster", "", "group nodes into clusters: phase|principal")
+		color := fs.String("color", "", "fill nodes by: trust|risk")
+		weights := fs.String("weights", "", "score model JSON for -color risk")
+		types := fs.String("types", "", "comma-separated node types to keep (e.g. Principal,Step)")
+		maxNodes := fs.Int("max-nodes", 0, "keep only the N most connected (or riskiest) nodes")
 		fs.Parse(os.Args[2:])
 
 		if *in == "" {
 			fs.Usage()
 			os.Exit(2)
 		}
+		if *out == "" {
+			*out = "graph." + *format
+		}
+		switch strings.ToUpper(*rankdir) {
+		case "", "TB", "LR", "BT", "RL":
+		default:
+			log.Fatalf("unknown rankdir %q (TB|LR|BT|RL)", *rankdir)
+		}
+
+		g := loadGraph(*in)
+		var err error
 
-		graphJSON, err := os.ReadFile(*in)
+		opts := graph.DOTOptions{RankDir: *rankdir, ColorBy: *color, MaxNodes: *maxNodes}
+		if *types != "" {
+			opts.Types = strings.Split(*types, ",")
+		}
+		opts.Clusters, err = condense.Clusters(g, *cluster)
 		if err != nil {
 			log.Fatal(err)
 		}
+		switch *color {
+		case "", "trust":
+		case "risk":
+			m := risk.DefaultScoreModel()
+			if *weights != "" {
+				m, err = risk.LoadScoreModel(*weights)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			sr := risk.ComputeScores(g, m)
+			opts.Scores = map[string]float64{}
+			for _, n := range append(sr.Nodes, sr.Artifacts...) {
+				opts.Scores[n.ID] = n.Score
+			}
+		default:
+			log.Fatalf("unknown color %q (trust|risk)", *color)
+		}
 
-		var g graph.AstraGraph
-		if err := json.Unmarshal(graphJSON, &g); err != nil {
+		var doc string
+		switch *format {
+		case "dot":
+			doc = graph.ToDOTWith(g, opts)
+		case "html":
+			doc, err = graph.ToHTML(g, filepath.Base(*in), opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+		default:
+			log.Fatalf("unknown format %q (dot|html)", *format)
+		}
+		if err := os.WriteFile(*out, []byte(doc), 0644); err != nil {
 			log.Fatal(err)
 		}
+		fmt.Printf("[OK] %s graph written to %s\n", strings.ToUpper(*format), *out)
 
-		dot := graph.ToDOT(g)
-		if err := os.WriteFile(*out, []byte(dot), 0644); err != nil {
-			log.Fatal(err)
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		in := fs.String("i", "", "input graph JSON (mermaid and plantuml also take astra condense output)")
+		format := fs.String("format", "graphml", "graphml|gexf|cypher|csv (Neo4j bulk import)|mermaid|plantuml")
+		out := fs.String("o", "", "output file, or directory for csv (default graph.<format>, neo4j/ for csv)")
+		rankdir := fs.String("rankdir", "", "mermaid/plantuml: layout direction TB|LR|BT|RL")
+		cluster := fs.String("cluster", "", "mermaid/plantuml: group nodes by phase|principal")
+		types := fs.String("types", "", "mermaid/plantuml: comma-separated node types to keep")
+		maxNodes := fs.Int("max-nodes", 0, "mermaid/plantuml: keep only the N most connected nodes")
+		fs.Parse(os.Args[2:])
+		if *in == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		b, err := os.ReadFile(*in)
+		must(err)
+		var probe struct {
+			GroupBy string `json:"group_by"`
+		}
+		must(json.Unmarshal(b, &probe))
+		diagram := *format == "mermaid" || *format == "plantuml"
+		if probe.GroupBy != "" && !diagram {
+			fmt.Fprintf(os.Stderr, "[FAIL] %s is a condensed graph; only mermaid and plantuml can export it\n", *in)
+			os.Exit(2)
+		}
+		var g graph.AstraGraph
+		if probe.GroupBy == "" {
+			g = loadGraph(*in)
+		}
+
+		if *out == "" {
+			switch *format {
+			case "csv":
+				*out = "neo4j"
+			case "mermaid":
+				*out = "graph.mmd"
+			case "plantuml":
+				*out = "graph.puml"
+			default:
+				*out = "graph." + *format
+			}
+		}
+		var doc []byte
+		switch *format {
+		case "graphml":
+			doc, err = graph.ToGraphML(g)
+		case "gexf":
+			doc, err = graph.ToGEXF(g)
+		case "cypher":
+			doc = []byte(graph.ToCypher(g))
+		case "csv":
+			nodes, edges, err := graph.ToNeo4jCSV(g)
+			must(err)
+			must(os.MkdirAll(*out, 0o755))
+			must(os.WriteFile(filepath.Join(*out, "nodes.csv"), nodes, 0o644))
+			must(os.WriteFile(filepath.Join(*out, "edges.csv"), edges, 0o644))
+			fmt.Printf("[OK] Neo4j import files -> %s (neo4j-admin database import full --multiline-fields=true --nodes=nodes.csv --relationships=edges.csv)\n", *out)
+		case "mermaid", "plantuml":
+			var d graph.Diagram
+			if probe.GroupBy != "" {
+				var cg condense.Graph
+				must(json.Unmarshal(b, &cg))
+				d = cg.Diagram(*rankdir)
+			} else {
+				opts := graph.DOTOptions{RankDir: *rankdir, MaxNodes: *maxNodes}
+				if *types != "" {
+					opts.Types = strings.Split(*types, ",")
+				}
+				opts.Clusters, err = condense.Clusters(g, *cluster)
+				must(err)
+				d = graph.DiagramOf(g, opts)
+			}
+			if len(d.Nodes) > 100 {
+				fmt.Fprintf(os.Stderr, "[WARN] %d nodes will not render legibly; narrow with -types, -max-nodes, astra trace or astra condense\n", len(d.Nodes))
+			}
+			text := graph.ToPlantUML(d)
+			if *format == "mermaid" {
+				text = graph.ToMermaid(d)
+				if strings.EqualFold(filepath.Ext(*out), ".md") {
+					text = "```mermaid\n" + text + "```\n"
+				}
+			}
+			doc = []byte(text)
+		default:
+			fmt.Fprintf(os.Stderr, "[FAIL] unknown format %q (graphml|gexf|cypher|csv|mermaid|plantuml)\n", *format)
+			os.Exit(2)
+		}
+		if *format == "csv" {
+			break
+		}
+		must(err)
+		must(os.MkdirAll(filepath.Dir(*out), 0o755))
+		must(os.WriteFile(*out, doc, 0o644))
+		fmt.Printf("[OK] %s export -> %s\n", strings.ToUpper(*format), *out)
+
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		in := fs.String("i", "", "input file (GUAC GraphQL JSON or SPDX 2.x JSON)")
+		format := fs.String("f", "spdx", "format of input (guac|spdx)")
+		out := fs.String("o", "", "output graph JSON")
+		fs.Parse(os.Args[2:])
+		if *in == "" || *out == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		g, warnings, err := importer.Load(*in, *format)
+		must(err)
+		for _, w := range warnings {
+			fmt.Fprintln(os.Stderr, "[WARN]", w)
+		}
+		must(writeJSON(*out, g))
+		fmt.Printf("[OK] Imported %d nodes, %d edges -> %s\n", len(g.Artifacts)+len(g.Steps)+len(g.Principals)+len(g.Resources), len(g.Edges), *out)
+
+	case "migrate":
+		var ins inputList
+		fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+		fs.Var(&ins, "i", "graph or parsed JSON to upgrade; repeatable, accepts directories and globs")
+		out := fs.String("o", "", "output file (default: rewrite the input in place; one -i only)")
+		fs.Parse(os.Args[2:])
+		if len(ins) == 0 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		files, err := expandInputs(ins)
+		must(err)
+		if *out != "" && len(files) != 1 {
+			must(fmt.Errorf("-o needs exactly one input, got %d", len(files)))
+		}
+		failed := 0
+		for _, f := range files {
+			dst := f
+			if *out != "" {
+				dst = *out
+			}
+			kind, from, to, notes, err := schema.Migrate(f, dst)
+			if errors.Is(err, schema.ErrUnversioned) && len(files) > 1 {
+				fmt.Fprintln(os.Stderr, "[WARN] skipped", err)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "[FAIL]", err)
+				failed++
+				continue
+			}
+			for _, n := range notes {
+				fmt.Printf("  - %s\n", n)
+			}
+			if from == to && f == dst {
+				fmt.Printf("[OK] %s is already %s v%d\n", f, kind, to)
+				continue
+			}
+			fmt.Printf("[OK] %s: %s v%d -> v%d -> %s\n", f, kind, from, to, dst)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+
+	case "schema":
+		fs := flag.NewFlagSet("schema", flag.ExitOnError)
+		out := fs.String("o", "schema", "output directory for the JSON Schema documents")
+		fs.Parse(os.Args[2:])
+		paths, err := schema.Write(*out)
+		must(err)
+		for _, p := range paths {
+			fmt.Println("[OK] Schema ->", p)
 		}
-		fmt.Println("[OK] DOT graph written to", *out)
 
 	default:
 		fmt.Println("unknown subcommand:", sub)
@@ -0,0 +1,333 @@
+package assess
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/query"
+)
+
+type Options struct {
+	// TrustedBuilders are builder ids / names accepted as hardened build
+	// platforms, in addition to principals the mapper marked "verified".
+	TrustedBuilders []string
+	// AllArtifacts assesses every produced artifact, not only releases.
+	AllArtifacts bool
+}
+
+// Requirement is one SLSA requirement with the nodes that satisfy it, or
+// the gap that keeps it from being met.
+type Requirement struct {
+	Track    string   `json:"track"` // build|source
+	Level    int      `json:"level"`
+	ID       string   `json:"id"`
+	Met      bool     `json:"met"`
+	Unknown  bool     `json:"unknown,omitempty"` // the graph does not record the evidence either way; not met
+	Evidence []string `json:"evidence,omitempty"`
+	Gap      string   `json:"gap,omitempty"`
+}
+
+type ArtifactAssessment struct {
+	Artifact     string        `json:"artifact"`
+	BuildLevel   int           `json:"build_level"`
+	SourceLevel  int           `json:"source_level"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+type Report struct {
+	Artifacts []ArtifactAssessment `json:"artifacts"`
+}
+
+/*
+AssessSLSA estimates which SLSA requirements each release artifact meets,
+from the same graph every other command uses. It is an estimate: the graph
+records what our parsers saw, not what the build platform guarantees.
+
+Build track:
+
+	L1 provenance-exists  the producing step is attested: a GUAC HasSLSA step, a
+	                      step with provenance or predicate_type metadata, or an
+	                      attestation artifact next to the step or the artifact
+	L2 hosted-builder     that step was performed by a builder (Builder / builder_id)
+	L2 signed-provenance  the builder signed it (signing key recorded)
+	L3 trusted-builder    the builder is trusted (trust=verified or in TrustedBuilders)
+	L3 non-falsifiable    the signature was verified, not just present
+
+Source track (over every commit step upstream of the artifact):
+
+	L1 version-controlled upstream source comes from version control commits
+	L2 verified-history   every commit is signed (signing_key on the commit artifact)
+	L3 two-party-review   every commit has at least two distinct principals
+
+A level is reached when all requirements of it and every lower level are met.
+Requirements the graph holds no evidence for either way are unknown, which
+counts as not met: builder requirements when no principal performs the
+producing step, and two-party-review when no commit records a second
+principal (git history has one author per commit; reviews happen
+elsewhere). Releases are produced artifacts that no step consumes.
+*/
+func AssessSLSA(g graph.AstraGraph, opts Options) Report {
+	ix := graph.NewIndex(g)
+	princs := map[string]graph.Principal{}
+	for _, p := range g.Principals {
+		princs[p.ID] = p
+	}
+	arts := map[string]graph.Artifact{}
+	for _, a := range g.Artifacts {
+		arts[a.ID] = a
+	}
+	steps := map[string]graph.Step{}
+	for _, s := range g.Steps {
+		steps[s.ID] = s
+	}
+
+	performers := func(step string) []graph.Principal {
+		var out []graph.Principal
+		for _, e := range ix.In[step] {
+			if p, ok := princs[e.Source]; ok && e.Relation == "performs" {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	var rep Report
+	for _, a := range g.Artifacts {
+		producers := producersOf(ix, a.ID)
+		if len(producers) == 0 {
+			continue
+		}
+		if !opts.AllArtifacts && (consumed(ix, a.ID) || strings.HasPrefix(a.Kind, "git-")) {
+			continue
+		}
+
+		var reqs []Requirement
+
+		// --- Build track ---
+		var builders []graph.Principal
+		performed := false
+		for _, s := range producers {
+			for _, p := range performers(s) {
+				performed = true
+				if p.Builder != "" || p.Metadata["builder_id"] != "" {
+					builders = append(builders, p)
+				}
+			}
+		}
+		prov := Requirement{Track: "build", Level: 1, ID: "provenance-exists", Evidence: provenance(ix, steps, arts, a.ID, producers)}
+		if prov.Met = len(prov.Evidence) > 0; !prov.Met {
+			prov.Gap = "no SLSA or in-toto provenance for the producing step"
+		}
+		reqs = append(reqs, prov)
+		first := len(reqs)
+		reqs = append(reqs, check("build", 2, "hosted-builder", builders, func(p graph.Principal) bool { return true },
+			"producing step has no builder principal"))
+		reqs = append(reqs, check("build", 2, "signed-provenance", builders, func(p graph.Principal) bool { return graph.SigningKey(p.Metadata) != "" },
+			"no builder signing key recorded"))
+		reqs = append(reqs, check("build", 3, "trusted-builder", builders, func(p graph.Principal) bool {
+			return p.Trust == "verified" || inList(opts.TrustedBuilders, p.Builder) || inList(opts.TrustedBuilders, p.Metadata["builder_id"])
+		}, "builder is not trusted (trust_level != verified and not in trusted builders)"))
+		reqs = append(reqs, check("build", 3, "non-falsifiable", builders, func(p graph.Principal) bool {
+			switch strings.ToLower(p.Metadata["signature"]) {
+			case "verified", "good", "valid":
+				return true
+			}
+			return false
+		}, "no verified builder signature"))
+		if !performed {
+			for i := first; i < len(reqs); i++ {
+				reqs[i].Unknown, reqs[i].Gap = true, "no principal recorded for the producing step"
+			}
+		}
+
+		// --- Source track ---
+		commits := upstreamCommits(g, ix, arts, a.ID)
+		srcVC := Requirement{Track: "source", Level: 1, ID: "version-controlled", Met: len(commits) > 0, Evidence: commits}
+		if !srcVC.Met {
+			srcVC.Gap = "no version-controlled source upstream"
+		}
+		reqs = append(reqs, srcVC)
+		reqs = append(reqs, everyCommit("source", 2, "verified-history", commits, func(step string) bool {
+			return commitKey(ix, arts, step) != ""
+		}, "commits without a signing key"))
+		review := everyCommit("source", 3, "two-party-review", commits, func(step string) bool {
+			return len(performers(step)) >= 2
+		}, "commits with a single principal")
+		if len(commits) > 0 && len(review.Evidence) == 0 {
+			review.Unknown, review.Gap = true, "no commit records a reviewer; reviews are not in the graph"
+		}
+		reqs = append(reqs, review)
+
+		rep.Artifacts = append(rep.Artifacts, ArtifactAssessment{
+			Artifact:     a.ID,
+			BuildLevel:   level(reqs, "build"),
+			SourceLevel:  level(reqs, "source"),
+			Requirements: reqs,
+		})
+	}
+	return rep
+}
+
+// provenanceKinds are artifact kinds of build attestations.
+var provenanceKinds = []string{"provenance", "slsa-provenance", "in-toto-link", "in-toto-statement", "attestation"}
+
+// provenance returns the attestations of the steps that produced id: the
+// steps themselves when they came from one, and attestation artifacts
+// linked to a step or to id.
+func provenance(ix *graph.Index, steps map[string]graph.Step, arts map[string]graph.Artifact, id string, producers []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	attestation := func(v string) bool {
+		a, ok := arts[v]
+		return ok && inList(provenanceKinds, a.Kind)
+	}
+	for _, n := range append([]string{id}, producers...) {
+		if md := steps[n].Metadata; md["guac_type"] == "HasSLSA" || md["provenance"] != "" || md["predicate_type"] != "" {
+			add(n)
+		}
+		for _, e := range ix.In[n] {
+			if attestation(e.Source) {
+				add(e.Source)
+			}
+		}
+		for _, e := range ix.Out[n] {
+			if attestation(e.Target) {
+				add(e.Target)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// check is met when at least one candidate satisfies ok.
+func check(track string, lvl int, id string, cands []graph.Principal, ok func(graph.Principal) bool, gap string) Requirement {
+	r := Requirement{Track: track, Level: lvl, ID: id}
+	for _, p := range cands {
+		if ok(p) {
+			r.Evidence = append(r.Evidence, p.ID)
+		}
+	}
+	r.Met = len(r.Evidence) > 0
+	if !r.Met {
+		r.Gap = gap
+	}
+	return r
+}
+
+// everyCommit is met when ok holds for every commit step.
+func everyCommit(track string, lvl int, id string, commits []string, ok func(step string) bool, gap string) Requirement {
+	r := Requirement{Track: track, Level: lvl, ID: id}
+	var missing []string
+	for _, s := range commits {
+		if ok(s) {
+			r.Evidence = append(r.Evidence, s)
+		} else {
+			missing = append(missing, s)
+		}
+	}
+	r.Met = len(commits) > 0 && len(missing) == 0
+	switch {
+	case len(commits) == 0:
+		r.Gap = "no version-controlled source upstream"
+	case len(missing) > 0:
+		r.Gap = fmt.Sprintf("%d of %d %s (first: %s)", len(missing), len(commits), gap, missing[0])
+	}
+	return r
+}
+
+// level is the highest level whose requirements, and all below, are met.
+func level(reqs []Requirement, track string) int {
+	lvl := 0
+	for l := 1; ; l++ {
+		found := false
+		for _, r := range reqs {
+			if r.Track != track || r.Level != l {
+				continue
+			}
+			found = true
+			if !r.Met {
+				return lvl
+			}
+		}
+		if !found {
+			return lvl
+		}
+		lvl = l
+	}
+}
+
+func producersOf(ix *graph.Index, id string) []string {
+	var out []string
+	for _, e := range ix.In[id] {
+		if e.Relation == "produces" {
+			out = append(out, e.Source)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func consumed(ix *graph.Index, id string) bool {
+	for _, e := range ix.In[id] {
+		if e.Relation == "consumes" {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamCommits returns the steps upstream of id that produced a git commit.
+func upstreamCommits(g graph.AstraGraph, ix *graph.Index, arts map[string]graph.Artifact, id string) []string {
+	nodes, err := query.Upstream(g, id, 0)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, n := range nodes {
+		if n.Type != graph.TypeStep {
+			continue
+		}
+		for _, e := range ix.Out[n.ID] {
+			if e.Relation == "produces" && arts[e.Target].Kind == "git-commit" {
+				out = append(out, n.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// commitKey returns the signing key of the git commit a step produced;
+// the git parser records it on the commit artifact, not the author.
+func commitKey(ix *graph.Index, arts map[string]graph.Artifact, step string) string {
+	for _, e := range ix.Out[step] {
+		if a, ok := arts[e.Target]; ok && e.Relation == "produces" && a.Kind == "git-commit" {
+			if k := graph.SigningKey(a.Metadata); k != "" {
+				return k
+			}
+		}
+	}
+	return ""
+}
+
+func inList(list []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package assess
+
+import (
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+func requirement(t *testing.T, a ArtifactAssessment, id string) Requirement {
+	t.Helper()
+	for _, r := range a.Requirements {
+		if r.ID == id {
+			return r
+		}
+	}
+	t.Fatalf("%s: no requirement %s", a.Artifact, id)
+	return Requirement{}
+}
+
+// release builds one .deb from one git commit; md is the build step's
+// metadata, performers its principals.
+func release(md map[string]string, performers ...graph.Principal) graph.AstraGraph {
+	g := graph.AstraGraph{
+		Principals: append([]graph.Principal{{ID: "principal:dev"}}, performers...),
+		Steps: []graph.Step{
+			{ID: "step:commit", Command: "git commit"},
+			{ID: "step:build", Command: "dpkg-buildpackage", Metadata: md},
+		},
+		Artifacts: []graph.Artifact{
+			{ID: "artifact:gitcommit:github.com/a/r@1", Kind: "git-commit", Metadata: map[string]string{"signing_key": "AAAA"}},
+			{ID: "hello.deb", Kind: "binary"},
+		},
+		Edges: []graph.Edge{
+			{Source: "principal:dev", Target: "step:commit", Relation: "performs"},
+			{Source: "step:commit", Target: "artifact:gitcommit:github.com/a/r@1", Relation: "produces"},
+			{Source: "step:build", Target: "artifact:gitcommit:github.com/a/r@1", Relation: "consumes"},
+			{Source: "step:build", Target: "hello.deb", Relation: "produces"},
+		},
+	}
+	for _, p := range performers {
+		g.Edges = append(g.Edges, graph.Edge{Source: p.ID, Target: "step:build", Relation: "performs"})
+	}
+	return g
+}
+
+func TestProvenanceNeedsAttestation(t *testing.T) {
+	builder := graph.Principal{ID: "Debian", Builder: "Debian Build Infrastructure", Metadata: map[string]string{"pgp_key_id": "BBBB"}}
+
+	a := AssessSLSA(release(nil, builder), Options{}).Artifacts[0]
+	if r := requirement(t, a, "provenance-exists"); r.Met {
+		t.Errorf("provenance met without an attestation: %+v", r)
+	}
+	if r := requirement(t, a, "hosted-builder"); !r.Met || r.Unknown {
+		t.Errorf("hosted-builder %+v, want met", r)
+	}
+	if a.BuildLevel != 0 {
+		t.Errorf("build level %d, want 0", a.BuildLevel)
+	}
+
+	a = AssessSLSA(release(map[string]string{"guac_type": "HasSLSA"}, builder), Options{}).Artifacts[0]
+	if r := requirement(t, a, "provenance-exists"); !r.Met || r.Evidence[0] != "step:build" {
+		t.Errorf("provenance %+v, want the HasSLSA step", r)
+	}
+	if a.BuildLevel != 2 {
+		t.Errorf("build level %d, want 2 (signed, not verified)", a.BuildLevel)
+	}
+
+	g := release(nil, builder)
+	g.Artifacts = append(g.Artifacts, graph.Artifact{ID: "hello.intoto.jsonl", Kind: "in-toto-link"})
+	g.Edges = append(g.Edges, graph.Edge{Source: "step:build", Target: "hello.intoto.jsonl", Relation: "produces"})
+	a = AssessSLSA(g, Options{}).Artifacts[0]
+	if r := requirement(t, a, "provenance-exists"); !r.Met || r.Evidence[0] != "hello.intoto.jsonl" {
+		t.Errorf("provenance %+v, want the in-toto link", r)
+	}
+}
+
+func TestUnknownRequirements(t *testing.T) {
+	a := AssessSLSA(release(nil), Options{}).Artifacts[0]
+	for _, id := range []string{"hosted-builder", "signed-provenance", "trusted-builder", "non-falsifiable"} {
+		if r := requirement(t, a, id); r.Met || !r.Unknown {
+			t.Errorf("%s %+v, want unknown without a performer", id, r)
+		}
+	}
+	if r := requirement(t, a, "verified-history"); !r.Met {
+		t.Errorf("verified-history %+v, want met by the commit's key", r)
+	}
+	// one author per commit says nothing about review
+	if r := requirement(t, a, "two-party-review"); r.Met || !r.Unknown {
+		t.Errorf("two-party-review %+v, want unknown", r)
+	}
+	if a.SourceLevel != 2 {
+		t.Errorf("source level %d, want 2", a.SourceLevel)
+	}
+
+	g := release(nil)
+	g.Principals = append(g.Principals, graph.Principal{ID: "principal:reviewer"})
+	g.Edges = append(g.Edges, graph.Edge{Source: "principal:reviewer", Target: "step:commit", Relation: "performs"})
+	a = AssessSLSA(g, Options{}).Artifacts[0]
+	if r := requirement(t, a, "two-party-review"); !r.Met || r.Unknown || a.SourceLevel != 3 {
+		t.Errorf("two-party-review %+v at source L%d, want met at L3", r, a.SourceLevel)
+	}
+}
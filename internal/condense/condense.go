@@ -1 +1,342 @@
 package condense
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Node is a super-node: every original node mapped to the same group.
+type Node struct {
+	ID    string         `json:"id"`
+	Group string         `json:"group"`
+	Size  int            `json:"size"`
+	Types map[string]int `json:"types"`           // members per node type
+	Start string         `json:"start,omitempty"` // earliest member step time (RFC3339)
+	End   string         `json:"end,omitempty"`   // latest member step time
+
+	// dir and package rollups
+	Kind       string   `json:"kind,omitempty"`     // package ecosystem (go|npm|python)
+	Files      int      `json:"files,omitempty"`    // distinct file paths
+	Versions   int      `json:"versions,omitempty"` // git file versions
+	Principals []string `json:"principals,omitempty"`
+
+	Members []string `json:"members"`
+}
+
+// Edge aggregates original edges between two super-nodes with the same
+// relation. Source == Target counts edges inside a group.
+type Edge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Relation string `json:"relation"`
+	Count    int    `json:"count"`
+}
+
+type Graph struct {
+	GroupBy string `json:"group_by"`
+	Nodes   []Node `json:"nodes"`
+	Edges   []Edge `json:"edges"`
+}
+
+// Modes accepted by Condense.
+const (
+	ByPhase = "phase"
+	ByType  = "type"
+)
+
+// Condense collapses g into super-nodes by group with default options.
+func Condense(g graph.AstraGraph, group string) (Graph, error) {
+	return CondenseWith(g, group, Options{})
+}
+
+// CondenseWith collapses g into super-nodes by group
+// (phase|type|session|day|week|release|scc|dir|package).
+func CondenseWith(g graph.AstraGraph, group string, opts Options) (Graph, error) {
+	ix := graph.NewIndex(g)
+	times := StepTimes(g, ix)
+	var key map[string]string
+	switch group {
+	case ByPhase:
+		key = Phases(g, ix)
+	case ByType:
+		key = map[string]string{}
+		for _, id := range ix.IDs {
+			key[id] = ix.Types[id]
+		}
+	case BySession:
+		key = sessions(g, ix, times, opts)
+	case ByDay, ByWeek, ByRelease:
+		key = buckets(g, ix, times, group)
+	case ByDir, ByPackage:
+		var kind map[string]string
+		key, kind = rollup(g, group, opts)
+		cg := Collapse(g, ix, group, key)
+		annotateRollup(&cg, ix, kind)
+		return cg, nil
+	case BySCC:
+		key = map[string]string{}
+		for _, c := range SCCs(g) {
+			for _, m := range c.Members {
+				key[m] = strings.TrimPrefix(c.ID, "scc:")
+			}
+		}
+	default:
+		return Graph{}, fmt.Errorf("unknown grouping %q (phase|type|session|day|week|release|scc|dir|package)", group)
+	}
+	cg := Collapse(g, ix, group, key)
+	for i := range cg.Nodes {
+		n := &cg.Nodes[i]
+		var first, last time.Time
+		for _, m := range n.Members {
+			t, ok := times[m]
+			if !ok {
+				continue
+			}
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+			if t.After(last) {
+				last = t
+			}
+		}
+		if !first.IsZero() {
+			n.Start, n.End = first.UTC().Format(time.RFC3339), last.UTC().Format(time.RFC3339)
+		}
+	}
+	return cg, nil
+}
+
+/*
+Collapse builds the condensed graph for an assignment of node IDs to group
+keys. Super-node IDs are "<mode>:<key>"; nodes without a key keep their own
+ID as a singleton group, so nothing is lost. Output is sorted, so the same
+input always condenses to the same JSON.
+*/
+func Collapse(g graph.AstraGraph, ix *graph.Index, mode string, key map[string]string) Graph {
+	super := func(id string) string {
+		if k, ok := key[id]; ok && k != "" {
+			return mode + ":" + k
+		}
+		return id
+	}
+
+	nodes := map[string]*Node{}
+	for _, id := range ix.IDs {
+		sid := super(id)
+		n, ok := nodes[sid]
+		if !ok {
+			grp := key[id]
+			if grp == "" {
+				grp = id
+			}
+			n = &Node{ID: sid, Group: grp, Types: map[string]int{}}
+			nodes[sid] = n
+		}
+		n.Size++
+		n.Types[ix.Types[id]]++
+		n.Members = append(n.Members, id)
+	}
+
+	type ek struct{ s, t, r string }
+	counts := map[ek]int{}
+	for _, e := range g.Edges {
+		counts[ek{super(e.Source), super(e.Target), e.Relation}]++
+	}
+
+	out := Graph{GroupBy: mode}
+	for _, sid := range sortedNodeIDs(nodes) {
+		out.Nodes = append(out.Nodes, *nodes[sid])
+	}
+	for k, c := range counts {
+		out.Edges = append(out.Edges, Edge{Source: k.s, Target: k.t, Relation: k.r, Count: c})
+	}
+	sort.Slice(out.Edges, func(i, j int) bool {
+		a, b := out.Edges[i], out.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Relation < b.Relation
+	})
+	return out
+}
+
+/*
+Phases assigns every node a phase (source/build/release...).
+
+Steps use their "phase" metadata when a parser set it (git commits are
+"source"); otherwise the command decides: publishing and signing commands
+are "release", commits "source", anything else "build". Other nodes take
+the phase of the steps they are attached to, preferring the producing step
+for artifacts; when the attached steps disagree, the earliest phase in
+source < build < release wins. Nodes attached to no step are "unphased".
+*/
+func Phases(g graph.AstraGraph, ix *graph.Index) map[string]string {
+	out := map[string]string{}
+	for _, s := range g.Steps {
+		out[s.ID] = stepPhase(s)
+	}
+
+	for _, id := range ix.IDs {
+		if _, ok := out[id]; ok {
+			continue
+		}
+		var produced, other []string
+		for _, e := range ix.In[id] {
+			if p, ok := phaseOfStep(out, ix, e.Source); ok {
+				if e.Relation == "produces" {
+					produced = append(produced, p)
+				} else {
+					other = append(other, p)
+				}
+			}
+		}
+		for _, e := range ix.Out[id] {
+			if p, ok := phaseOfStep(out, ix, e.Target); ok {
+				other = append(other, p)
+			}
+		}
+		switch {
+		case len(produced) > 0:
+			out[id] = earliest(produced)
+		case len(other) > 0:
+			out[id] = earliest(other)
+		default:
+			out[id] = "unphased"
+		}
+	}
+	return out
+}
+
+/*
+Clusters assigns nodes to drawing clusters for graph.DOTOptions: by phase,
+or by principal, where a principal's cluster holds it, the steps it performs
+and what those steps produce. Steps with several performers cluster under
+the joined names; resources and unattached nodes stay outside.
+*/
+func Clusters(g graph.AstraGraph, by string) (map[string]string, error) {
+	ix := graph.NewIndex(g)
+	switch by {
+	case "":
+		return nil, nil
+	case ByPhase:
+		return Phases(g, ix), nil
+	case "principal":
+		out := map[string]string{}
+		for _, p := range g.Principals {
+			out[p.ID] = p.ID
+		}
+		for _, s := range g.Steps {
+			who := performers(ix, s.ID)
+			if who == "" {
+				continue
+			}
+			out[s.ID] = who
+			for _, e := range ix.Out[s.ID] {
+				if e.Relation == "produces" {
+					out[e.Target] = who
+				}
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown clustering %q (phase|principal)", by)
+}
+
+func phaseOfStep(phases map[string]string, ix *graph.Index, id string) (string, bool) {
+	if ix.Types[id] != graph.TypeStep {
+		return "", false
+	}
+	return phases[id], true
+}
+
+func stepPhase(s graph.Step) string {
+	if p := strings.ToLower(strings.TrimSpace(s.Metadata["phase"])); p != "" {
+		return p
+	}
+	cmd := strings.ToLower(s.Command)
+	for _, w := range []string{"publish", "release", "upload", "dput", "sign", "deploy"} {
+		if strings.Contains(cmd, w) {
+			return "release"
+		}
+	}
+	if strings.Contains(cmd, "commit") || strings.HasPrefix(s.ID, "step:commit:") {
+		return "source"
+	}
+	return "build"
+}
+
+var phaseOrder = map[string]int{"source": 0, "build": 1, "release": 2}
+
+func earliest(ps []string) string {
+	sort.Slice(ps, func(i, j int) bool {
+		oi, ki := phaseOrder[ps[i]]
+		oj, kj := phaseOrder[ps[j]]
+		if ki != kj {
+			return ki // known phases first
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return ps[i] < ps[j]
+	})
+	return ps[0]
+}
+
+func sortedNodeIDs(m map[string]*Node) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+/*
+Diagram prepares a condensed graph for graph.ToMermaid and graph.ToPlantUML.
+Super-nodes are labelled with their group and size and drawn in the shape
+of their node type when all members share one; parallel edges show their
+count and edges inside a group are left out.
+*/
+func (cg Graph) Diagram(direction string) graph.Diagram {
+	d := graph.Diagram{Direction: direction}
+	for _, n := range cg.Nodes {
+		label, typ := n.Group, ""
+		if n.Size > 1 {
+			label = fmt.Sprintf("%s\n%d nodes", truncateLabel(n.Group, 40), n.Size)
+		} else {
+			label = truncateLabel(label, 40)
+		}
+		if len(n.Types) == 1 {
+			for t := range n.Types {
+				typ = t
+			}
+		}
+		d.Nodes = append(d.Nodes, graph.DiagramNode{ID: n.ID, Label: label, Type: typ})
+	}
+	for _, e := range cg.Edges {
+		if e.Source == e.Target {
+			continue
+		}
+		label := e.Relation
+		if e.Count > 1 {
+			label = fmt.Sprintf("%s ×%d", e.Relation, e.Count)
+		}
+		d.Edges = append(d.Edges, graph.DiagramEdge{Source: e.Source, Target: e.Target, Relation: e.Relation, Label: label})
+	}
+	return d
+}
+
+func truncateLabel(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
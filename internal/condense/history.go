@@ -0,0 +1,290 @@
+package condense
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Modes over commit history, accepted by Condense and CondenseWith.
+const (
+	BySession = "session"
+	ByDay     = "day"
+	ByWeek    = "week"
+	ByRelease = "release"
+)
+
+type Options struct {
+	// SessionGap splits a work session when consecutive commits are further
+	// apart than this; 0 keeps whole chains together.
+	SessionGap time.Duration
+	// DirDepth is the number of directory segments kept by the dir rollup
+	// (default 1).
+	DirDepth int
+	// Manifests overrides DefaultManifests for the package rollup.
+	Manifests map[string]string
+}
+
+/*
+sessions collapses linear chains of commit steps into work sessions. Two
+commits are chained when the later one has a single parent commit, that
+parent has no other child, and both were performed by the same principals.
+A session owns its commit steps and everything they produce (the commit
+and file artifacts), so a month of history becomes a handful of nodes.
+*/
+func sessions(g graph.AstraGraph, ix *graph.Index, times map[string]time.Time, opts Options) map[string]string {
+	isCommit := commitArtifacts(g)
+	commits := commitSteps(g, ix, isCommit)
+
+	producer := map[string]string{}
+	for _, s := range commits {
+		for _, e := range ix.Out[s] {
+			if e.Relation == "produces" {
+				producer[e.Target] = s
+			}
+		}
+	}
+	parents := func(s string) []string {
+		var out []string
+		for _, e := range ix.Out[s] {
+			if p, ok := producer[e.Target]; ok && e.Relation == "consumes" && isCommit[e.Target] {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+	children := map[string]int{}
+	for _, s := range commits {
+		for _, p := range parents(s) {
+			children[p]++
+		}
+	}
+
+	next := map[string]string{}
+	hasPrev := map[string]bool{}
+	for _, s := range commits {
+		ps := parents(s)
+		if len(ps) != 1 || children[ps[0]] != 1 {
+			continue
+		}
+		p := ps[0]
+		if performers(ix, p) != performers(ix, s) {
+			continue
+		}
+		if opts.SessionGap > 0 {
+			ts, ok1 := times[s]
+			tp, ok2 := times[p]
+			if !ok1 || !ok2 || ts.Sub(tp) > opts.SessionGap {
+				continue
+			}
+		}
+		next[p] = s
+		hasPrev[s] = true
+	}
+
+	key := map[string]string{}
+	for _, s := range commits {
+		if hasPrev[s] {
+			continue
+		}
+		name := s
+		if who := performers(ix, s); who != "" {
+			name = who + "@" + s
+		}
+		for cur := s; cur != ""; cur = next[cur] {
+			key[cur] = name
+			for _, e := range ix.Out[cur] {
+				if e.Relation == "produces" {
+					key[e.Target] = name
+				}
+			}
+		}
+	}
+	return key
+}
+
+/*
+buckets groups steps by the time interval they fall into, and the artifacts
+they produce with them. Day and week buckets are UTC calendar days and ISO
+weeks. Release buckets run from one release to the next: a release is a
+step in the "release" phase, or one producing an artifact that nothing
+consumes (a shipped output, not a git object); commits up to and including
+a release's time belong to it, later ones to "unreleased".
+*/
+func buckets(g graph.AstraGraph, ix *graph.Index, times map[string]time.Time, mode string) map[string]string {
+	var bucket func(time.Time) string
+	switch mode {
+	case ByDay:
+		bucket = func(t time.Time) string { return t.UTC().Format("2006-01-02") }
+	case ByWeek:
+		bucket = func(t time.Time) string {
+			y, w := t.UTC().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", y, w)
+		}
+	case ByRelease:
+		type rel struct {
+			at   time.Time
+			name string
+		}
+		var rels []rel
+		phases := Phases(g, ix)
+		arts := artifactsByID(g)
+		for _, s := range g.Steps {
+			t, ok := times[s.ID]
+			if !ok {
+				continue
+			}
+			if name, ok := releaseName(ix, arts, s, phases[s.ID]); ok {
+				rels = append(rels, rel{t, name})
+			}
+		}
+		sort.Slice(rels, func(i, j int) bool {
+			if !rels[i].at.Equal(rels[j].at) {
+				return rels[i].at.Before(rels[j].at)
+			}
+			return rels[i].name < rels[j].name
+		})
+		bucket = func(t time.Time) string {
+			i := sort.Search(len(rels), func(i int) bool { return !rels[i].at.Before(t) })
+			if i == len(rels) {
+				return "unreleased"
+			}
+			return rels[i].name
+		}
+	}
+
+	key := map[string]string{}
+	for _, s := range g.Steps {
+		t, ok := times[s.ID]
+		if !ok {
+			continue
+		}
+		b := bucket(t)
+		key[s.ID] = b
+		for _, e := range ix.Out[s.ID] {
+			if e.Relation == "produces" {
+				key[e.Target] = b
+			}
+		}
+	}
+	return key
+}
+
+// releaseName reports whether s is a release and names it after the
+// version of what it shipped, else its own ID.
+func releaseName(ix *graph.Index, arts map[string]graph.Artifact, s graph.Step, phase string) (string, bool) {
+	isRelease := phase == "release"
+	name := ""
+	for _, e := range ix.Out[s.ID] {
+		a, ok := arts[e.Target]
+		if e.Relation != "produces" || !ok || strings.HasPrefix(a.Kind, "git-") {
+			continue
+		}
+		consumed := false
+		for _, in := range ix.In[a.ID] {
+			if in.Relation == "consumes" {
+				consumed = true
+				break
+			}
+		}
+		if !consumed {
+			isRelease = true
+			if name == "" && a.Version != "" {
+				name = a.Version
+			}
+		}
+	}
+	if v := s.Metadata["version"]; v != "" {
+		name = v
+	}
+	if name == "" {
+		name = s.ID
+	}
+	return name, isRelease
+}
+
+// StepTimes returns when each step happened: its timestamp, else the
+// "time" of the git commit artifact it produced.
+func StepTimes(g graph.AstraGraph, ix *graph.Index) map[string]time.Time {
+	arts := artifactsByID(g)
+	out := map[string]time.Time{}
+	for _, s := range g.Steps {
+		if t, ok := graph.ParseTimestamp(s.Timestamp); ok {
+			out[s.ID] = t
+			continue
+		}
+		for _, e := range ix.Out[s.ID] {
+			if a, ok := arts[e.Target]; ok && e.Relation == "produces" {
+				if t, ok := graph.ParseTimestamp(a.Metadata["time"]); ok {
+					out[s.ID] = t
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+func commitSteps(g graph.AstraGraph, ix *graph.Index, isCommit map[string]bool) []string {
+	var out []string
+	for _, s := range g.Steps {
+		for _, e := range ix.Out[s.ID] {
+			if e.Relation == "produces" && isCommit[e.Target] {
+				out = append(out, s.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// commitArtifacts is the set of git commit artifacts in g.
+func commitArtifacts(g graph.AstraGraph) map[string]bool {
+	out := map[string]bool{}
+	for _, a := range g.Artifacts {
+		if a.Kind == "git-commit" || strings.HasPrefix(a.ID, "artifact:gitcommit:") {
+			out[a.ID] = true
+		}
+	}
+	return out
+}
+
+func artifactsByID(g graph.AstraGraph) map[string]graph.Artifact {
+	out := make(map[string]graph.Artifact, len(g.Artifacts))
+	for _, a := range g.Artifacts {
+		out[a.ID] = a
+	}
+	return out
+}
+
+// performers is the sorted, comma-joined set of principals performing s.
+func performers(ix *graph.Index, s string) string {
+	var ps []string
+	for _, e := range ix.In[s] {
+		if e.Relation == "performs" {
+			ps = append(ps, e.Source)
+		}
+	}
+	sort.Strings(ps)
+	return strings.Join(ps, ",")
+}
+
+// Expand returns the part of g a super-node stands for: its members and
+// the edges between them.
+func Expand(cg Graph, g graph.AstraGraph, id string) (graph.AstraGraph, error) {
+	for _, n := range cg.Nodes {
+		if n.ID != id {
+			continue
+		}
+		keep := map[string]bool{}
+		for _, m := range n.Members {
+			keep[m] = true
+		}
+		return graph.Subgraph(g, keep, nil), nil
+	}
+	return graph.AstraGraph{}, fmt.Errorf("super-node not found: %s", id)
+}
@@ -0,0 +1,139 @@
+package condense
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+// Rollups of git file artifacts, accepted by CondenseWith.
+const (
+	ByDir     = "dir"
+	ByPackage = "package"
+)
+
+// DefaultManifests maps manifest file names to the ecosystem of the
+// package rooted in their directory.
+var DefaultManifests = map[string]string{
+	"go.mod":         "go",
+	"package.json":   "npm",
+	"pyproject.toml": "python",
+	"setup.py":       "python",
+	"setup.cfg":      "python",
+}
+
+// gitFile returns the repository (as a purl) and path of a git file
+// artifact ID.
+func gitFile(id string) (repo, file string, ok bool) {
+	if !strings.HasPrefix(id, mapper.GitFilePrefix) {
+		return "", "", false
+	}
+	ident, ok := mapper.ParseArtifactID(id)
+	if !ok || ident.Subpath == "" {
+		return "", "", false
+	}
+	file = ident.Subpath
+	ident.Version, ident.Subpath = "", ""
+	return ident.PURL(), file, true
+}
+
+/*
+rollup assigns every git file version to a directory prefix of DirDepth
+segments (ByDir) or to the package whose manifest is the nearest ancestor
+of the file (ByPackage; files outside any package go to the repository
+root "."). Keys are "<repo>:<dir>", so two repositories never share a
+group. The ecosystem of each package key is returned alongside.
+*/
+func rollup(g graph.AstraGraph, mode string, opts Options) (key, kind map[string]string) {
+	depth := opts.DirDepth
+	if depth <= 0 {
+		depth = 1
+	}
+	manifests := opts.Manifests
+	if manifests == nil {
+		manifests = DefaultManifests
+	}
+
+	// package roots per repository, from every version of every path
+	roots := map[string]map[string]string{}
+	if mode == ByPackage {
+		for _, a := range g.Artifacts {
+			repo, file, ok := gitFile(a.ID)
+			if !ok {
+				continue
+			}
+			if eco, ok := manifests[path.Base(file)]; ok {
+				if roots[repo] == nil {
+					roots[repo] = map[string]string{}
+				}
+				roots[repo][path.Dir(file)] = eco
+			}
+		}
+	}
+
+	key, kind = map[string]string{}, map[string]string{}
+	for _, a := range g.Artifacts {
+		repo, file, ok := gitFile(a.ID)
+		if !ok {
+			continue
+		}
+		var dir string
+		if mode == ByDir {
+			dir = graph.DirPrefix(file, depth)
+		} else {
+			dir = "."
+			for d := path.Dir(file); ; d = path.Dir(d) {
+				if eco, ok := roots[repo][d]; ok {
+					dir = d
+					kind[repo+":"+d] = eco
+					break
+				}
+				if d == "." || d == "/" {
+					break
+				}
+			}
+		}
+		key[a.ID] = repo + ":" + dir
+	}
+	return key, kind
+}
+
+// annotateRollup adds the distinct file paths, file versions and the
+// principals whose steps produced them to each rolled-up node.
+func annotateRollup(cg *Graph, ix *graph.Index, kind map[string]string) {
+	for i := range cg.Nodes {
+		n := &cg.Nodes[i]
+		paths := map[string]bool{}
+		who := map[string]bool{}
+		for _, m := range n.Members {
+			_, file, ok := gitFile(m)
+			if !ok {
+				continue
+			}
+			n.Versions++
+			paths[file] = true
+			for _, e := range ix.In[m] {
+				if e.Relation != "produces" {
+					continue
+				}
+				for _, pe := range ix.In[e.Source] {
+					if pe.Relation == "performs" {
+						who[pe.Source] = true
+					}
+				}
+			}
+		}
+		if n.Versions == 0 {
+			continue
+		}
+		n.Files = len(paths)
+		n.Kind = kind[n.Group]
+		for p := range who {
+			n.Principals = append(n.Principals, p)
+		}
+		sort.Strings(n.Principals)
+	}
+}
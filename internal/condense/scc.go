@@ -0,0 +1,250 @@
+package condense
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+const BySCC = "scc"
+
+// Component is a strongly connected component with more than one node
+// (or a node with a self-loop): a cycle in the flow of influence.
+type Component struct {
+	ID      string   `json:"id"` // "scc:" + smallest member ID
+	Members []string `json:"members"`
+}
+
+/*
+SCCs returns the cycles of g over flow edges (graph.FlowEdge), using an
+iterative Tarjan so long commit histories cannot overflow the stack.
+Merging inputs can close cycles, e.g. a build dependency that is itself
+built from the repository it helps build.
+*/
+func SCCs(g graph.AstraGraph) []Component {
+	ix := graph.NewIndex(g)
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var comps []Component
+	next := 0
+
+	type frame struct {
+		v string
+		i int // next FlowOut position to visit
+	}
+	for _, root := range ix.IDs {
+		if _, ok := index[root]; ok {
+			continue
+		}
+		index[root], low[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+		call := []frame{{v: root}}
+
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			if f.i < len(ix.FlowOut[f.v]) {
+				w := ix.FlowOut[f.v][f.i]
+				f.i++
+				if _, ok := index[w]; !ok {
+					index[w], low[w] = next, next
+					next++
+					stack = append(stack, w)
+					onStack[w] = true
+					call = append(call, frame{v: w})
+				} else if onStack[w] && index[w] < low[f.v] {
+					low[f.v] = index[w]
+				}
+				continue
+			}
+
+			v := f.v
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				if p := call[len(call)-1].v; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] != index[v] {
+				continue
+			}
+			var members []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				members = append(members, w)
+				if w == v {
+					break
+				}
+			}
+			if len(members) > 1 || selfLoop(ix, v) {
+				sort.Strings(members)
+				comps = append(comps, Component{ID: "scc:" + members[0], Members: members})
+			}
+		}
+	}
+	sort.Slice(comps, func(i, j int) bool { return comps[i].ID < comps[j].ID })
+	return comps
+}
+
+func selfLoop(ix *graph.Index, v string) bool {
+	for _, w := range ix.FlowOut[v] {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Acyclic returns g with every cycle merged into one node, so topological
+analysis (astra risk) sees a DAG. The merged node is a Step when the cycle
+contains a step (every cycle through artifacts does), else an Artifact; its
+metadata lists the members. Edges inside a cycle are dropped, edges into or
+out of it are re-pointed and de-duplicated; relations are kept.
+*/
+func Acyclic(g graph.AstraGraph) (graph.AstraGraph, []Component) {
+	comps := SCCs(g)
+	if len(comps) == 0 {
+		return g, nil
+	}
+	ix := graph.NewIndex(g)
+	rep := map[string]string{}
+	for _, c := range comps {
+		for _, m := range c.Members {
+			rep[m] = c.ID
+		}
+	}
+	keep := map[string]bool{}
+	for _, id := range ix.IDs {
+		if _, merged := rep[id]; !merged {
+			keep[id] = true
+		}
+	}
+	out := graph.Subgraph(g, keep, func(graph.Edge) bool { return false })
+
+	for _, c := range comps {
+		md := map[string]string{
+			"members":  strings.Join(c.Members, ","),
+			"scc_size": strconv.Itoa(len(c.Members)),
+		}
+		step := false
+		for _, m := range c.Members {
+			if ix.Types[m] == graph.TypeStep {
+				step = true
+				break
+			}
+		}
+		if step {
+			out.Steps = append(out.Steps, graph.Step{ID: c.ID, Command: "cycle", Metadata: md})
+		} else {
+			out.Artifacts = append(out.Artifacts, graph.Artifact{ID: c.ID, Kind: "cycle", Name: c.ID, Metadata: md})
+		}
+	}
+
+	re := func(id string) string {
+		if r, ok := rep[id]; ok {
+			return r
+		}
+		return id
+	}
+	seen := map[[3]string]bool{}
+	for _, e := range g.Edges {
+		s, t := re(e.Source), re(e.Target)
+		if s == t && rep[e.Source] != "" {
+			continue
+		}
+		k := [3]string{s, t, e.Relation}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		e.Source, e.Target = s, t
+		out.Edges = append(out.Edges, e)
+	}
+	return out, comps
+}
+
+/*
+CoreNumbers computes each node's k-core number on the undirected, simple
+version of g (Batagelj-Zaversnik). principal --uses--> resource edges are
+left out, as in the other analyses: every principal touching resource:git
+would otherwise make the whole history look densely connected.
+*/
+func CoreNumbers(g graph.AstraGraph) map[string]int {
+	ix := graph.NewIndex(g)
+	adj := map[string]map[string]bool{}
+	for _, id := range ix.IDs {
+		adj[id] = map[string]bool{}
+	}
+	for _, e := range g.Edges {
+		if e.Relation == "uses" || e.Source == e.Target {
+			continue
+		}
+		if adj[e.Source] == nil || adj[e.Target] == nil {
+			continue
+		}
+		adj[e.Source][e.Target] = true
+		adj[e.Target][e.Source] = true
+	}
+
+	deg := map[string]int{}
+	maxDeg := 0
+	for id, n := range adj {
+		deg[id] = len(n)
+		if len(n) > maxDeg {
+			maxDeg = len(n)
+		}
+	}
+	// bucket queue by current degree; IDs sorted for determinism
+	bins := make([][]string, maxDeg+1)
+	for _, id := range ix.IDs {
+		bins[deg[id]] = append(bins[deg[id]], id)
+	}
+	core := map[string]int{}
+	done := map[string]bool{}
+	for d := 0; d <= maxDeg; d++ {
+		for len(bins[d]) > 0 {
+			v := bins[d][0]
+			bins[d] = bins[d][1:]
+			if done[v] || deg[v] != d {
+				continue // stale entry
+			}
+			done[v] = true
+			core[v] = d
+			for w := range adj[v] {
+				if !done[w] && deg[w] > d {
+					deg[w]--
+					bins[deg[w]] = append(bins[deg[w]], w)
+				}
+			}
+		}
+	}
+	return core
+}
+
+// KCore returns the subgraph of nodes with core number >= k, and the k
+// used; k <= 0 selects the maximum core, the densest heart of the graph.
+func KCore(g graph.AstraGraph, k int) (graph.AstraGraph, int) {
+	core := CoreNumbers(g)
+	if k <= 0 {
+		for _, c := range core {
+			if c > k {
+				k = c
+			}
+		}
+	}
+	keep := map[string]bool{}
+	for id, c := range core {
+		if c >= k {
+			keep[id] = true
+		}
+	}
+	return graph.Subgraph(g, keep, func(e graph.Edge) bool { return e.Relation != "uses" }), k
+}
@@ -0,0 +1,119 @@
+package graph
+
+import "fmt"
+
+// Diagram is a small, display-ready graph for text diagram formats
+// (Mermaid, PlantUML). Labels are already shortened.
+type Diagram struct {
+	Direction string // TB (default) | LR | BT | RL
+	Note      string // e.g. "40 of 120 nodes shown"
+	Nodes     []DiagramNode
+	Edges     []DiagramEdge
+}
+
+type DiagramNode struct {
+	ID    string
+	Label string
+	Type  string // node type, selects the shape; "" for a plain box
+	Group string // cluster, "" for none
+	Fill  string // "#rrggbb", "" for the default
+}
+
+type DiagramEdge struct {
+	Source   string
+	Target   string
+	Relation string // selects the line style
+	Label    string
+}
+
+// Fill colours, the CSS equivalents of the DOT palette.
+var trustFills = map[string]string{
+	"verified":  "#98fb98",
+	"trusted":   "#7ccd7c",
+	"signed":    "#fff68f",
+	"new":       "#ffa500",
+	"untrusted": "#ff6347",
+	"unknown":   "#d9d9d9",
+}
+
+func riskFill(s float64) string {
+	switch {
+	case s >= 75:
+		return "#ff6347"
+	case s >= 50:
+		return "#ffa500"
+	case s >= 25:
+		return "#fff68f"
+	default:
+		return "#98fb98"
+	}
+}
+
+/*
+DiagramOf prepares g for ToMermaid and ToPlantUML with the filters,
+clusters and colouring of DOTOptions. Text diagrams are meant for small
+graphs pasted into documents, so labels default to 40 runes; pick a trace
+or a condensed view (condense.Graph.Diagram) rather than a whole history.
+*/
+func DiagramOf(g AstraGraph, opts DOTOptions) Diagram {
+	if opts.MaxLabel <= 0 {
+		opts.MaxLabel = 40
+	}
+	ix := NewIndex(g)
+	keep, dropped := selectNodes(g, ix, opts)
+	labels := nodeLabels(g)
+	fill := map[string]string{}
+	switch opts.ColorBy {
+	case "trust":
+		for _, p := range g.Principals {
+			c, ok := trustFills[p.Trust]
+			if !ok {
+				c = trustFills["unknown"]
+			}
+			fill[p.ID] = c
+		}
+	case "risk":
+		for id, s := range opts.Scores {
+			fill[id] = riskFill(s)
+		}
+	}
+
+	d := Diagram{Direction: opts.RankDir}
+	if dropped > 0 {
+		d.Note = fmt.Sprintf("%d of %d nodes shown", len(keep), len(keep)+dropped)
+	}
+	for _, id := range ix.IDs {
+		if !keep[id] {
+			continue
+		}
+		d.Nodes = append(d.Nodes, DiagramNode{
+			ID:    id,
+			Label: truncate(firstNonEmpty(labels[id], id), opts.MaxLabel),
+			Type:  ix.Types[id],
+			Group: opts.Clusters[id],
+			Fill:  fill[id],
+		})
+	}
+	for _, e := range g.Edges {
+		if keep[e.Source] && keep[e.Target] {
+			d.Edges = append(d.Edges, DiagramEdge{Source: e.Source, Target: e.Target, Relation: e.Relation, Label: e.Relation})
+		}
+	}
+	return d
+}
+
+// diagramIDs gives every node a short, syntax-safe alias (n0, n1...) so
+// arbitrary IDs never have to appear unquoted, and lists the clusters in
+// order of first appearance.
+func diagramIDs(d Diagram) (alias map[string]string, groups []string, members map[string][]DiagramNode) {
+	alias = map[string]string{}
+	members = map[string][]DiagramNode{}
+	for i, n := range d.Nodes {
+		alias[n.ID] = fmt.Sprintf("n%d", i)
+		if _, ok := members[n.Group]; !ok && n.Group != "" {
+			groups = append(groups, n.Group)
+		}
+		members[n.Group] = append(members[n.Group], n)
+	}
+	return alias, groups, members
+}
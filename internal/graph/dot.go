@@ -0,0 +1,252 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DOTOptions controls ToDOTWith; the zero value renders every node.
+type DOTOptions struct {
+	RankDir  string             // TB (default) | LR | BT | RL
+	Clusters map[string]string  // node ID -> cluster name (see condense.Clusters)
+	ColorBy  string             // "" | trust | risk
+	Scores   map[string]float64 // 0-100 risk per node, for ColorBy risk
+	Types    []string           // keep only these node types (case-insensitive)
+	MaxNodes int                // keep the N most connected (or riskiest) nodes; 0 = all
+	MaxLabel int                // truncate labels to this many runes (default 60)
+}
+
+// ToDOT renders an AstraGraph into Graphviz DOT format with default options.
+func ToDOT(g AstraGraph) string {
+	return ToDOTWith(g, DOTOptions{})
+}
+
+var relationStyles = map[string]string{
+	"produces":     `color="black"`,
+	"consumes":     `color="steelblue" style="dashed"`,
+	"performs":     `color="darkgreen" style="bold"`,
+	"uses":         `color="gray60" style="dotted"`,
+	"carries_out":  `color="gray40" style="dashed"`,
+	"same_as":      `color="purple" style="dotted" dir="both"`,
+	"differs_from": `color="red" style="bold"`,
+}
+
+var trustColors = map[string]string{
+	"verified":  "palegreen",
+	"trusted":   "palegreen3",
+	"signed":    "khaki1",
+	"new":       "orange",
+	"untrusted": "tomato",
+	"unknown":   "gray85",
+}
+
+var typeShapes = map[string]string{
+	TypeArtifact:  "box",
+	TypeStep:      "diamond",
+	TypePrincipal: "oval",
+	TypeResource:  "hexagon",
+}
+
+/*
+ToDOTWith renders g as DOT. Every ID and label is escaped, so paths and
+commit messages with quotes, backslashes or newlines stay valid. Filters
+apply before layout: Types keeps the listed node types, then MaxNodes keeps
+the highest-scoring (ColorBy risk) or most connected nodes, ties by ID, and
+notes how many were dropped. Clusters group nodes into subgraph clusters;
+nodes without a cluster stay at the top level.
+*/
+func ToDOTWith(g AstraGraph, opts DOTOptions) string {
+	if opts.MaxLabel <= 0 {
+		opts.MaxLabel = 60
+	}
+	ix := NewIndex(g)
+
+	keep, dropped := selectNodes(g, ix, opts)
+	labels := nodeLabels(g)
+	attrs := map[string]string{}
+	for _, n := range g.Principals {
+		if opts.ColorBy == "trust" {
+			c, ok := trustColors[n.Trust]
+			if !ok {
+				c = trustColors["unknown"]
+			}
+			attrs[n.ID] = fmt.Sprintf(` style="filled" fillcolor="%s"`, c)
+		}
+	}
+	if opts.ColorBy == "risk" {
+		for id, s := range opts.Scores {
+			attrs[id] = fmt.Sprintf(` style="filled" fillcolor="%s"`, riskColor(s))
+		}
+	}
+
+	node := func(b *strings.Builder, indent, id string) {
+		typ := ix.Types[id]
+		label := truncate(firstNonEmpty(labels[id], id), opts.MaxLabel) + "\n(" + typ + ")"
+		if opts.ColorBy == "risk" {
+			if s, ok := opts.Scores[id]; ok {
+				label += fmt.Sprintf("\nrisk %.0f", s)
+			}
+		}
+		shape, ok := typeShapes[typ]
+		if !ok {
+			shape = "ellipse" // edge endpoint without a node
+		}
+		fmt.Fprintf(b, "%s%s [label=%s shape=%s%s];\n", indent, quote(id), quote(label), shape, attrs[id])
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph astra {\n")
+	if opts.RankDir != "" {
+		fmt.Fprintf(&b, "  rankdir=%s;\n", strings.ToUpper(opts.RankDir))
+	}
+	b.WriteString("  node [fontsize=10];\n  edge [fontsize=8];\n")
+	if dropped > 0 {
+		fmt.Fprintf(&b, "  label=%s;\n  labelloc=t;\n", quote(fmt.Sprintf("%d of %d nodes shown", len(keep), len(keep)+dropped)))
+	}
+
+	clusters := map[string][]string{}
+	for _, id := range ix.IDs {
+		if !keep[id] {
+			continue
+		}
+		if c := opts.Clusters[id]; c != "" {
+			clusters[c] = append(clusters[c], id)
+			continue
+		}
+		node(&b, "  ", id)
+	}
+	var names []string
+	for c := range clusters {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	for i, c := range names {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n    label=%s;\n    style=\"rounded\";\n", i, quote(c))
+		for _, id := range clusters[c] {
+			node(&b, "    ", id)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range g.Edges {
+		if !keep[e.Source] || !keep[e.Target] {
+			continue
+		}
+		style := relationStyles[e.Relation]
+		if style != "" {
+			style = " " + style
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s%s];\n", quote(e.Source), quote(e.Target), quote(e.Relation), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// selectNodes applies the Types and MaxNodes filters of opts and reports
+// how many nodes MaxNodes dropped.
+func selectNodes(g AstraGraph, ix *Index, opts DOTOptions) (map[string]bool, int) {
+	keep := map[string]bool{}
+	for _, id := range ix.IDs {
+		if len(opts.Types) == 0 || typeIn(ix.Types[id], opts.Types) {
+			keep[id] = true
+		}
+	}
+	dropped := 0
+	if opts.MaxNodes > 0 && len(keep) > opts.MaxNodes {
+		deg := map[string]int{}
+		for _, e := range g.Edges {
+			if keep[e.Source] && keep[e.Target] {
+				deg[e.Source]++
+				deg[e.Target]++
+			}
+		}
+		var ids []string
+		for id := range keep {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := ids[i], ids[j]
+			if opts.ColorBy == "risk" && opts.Scores[a] != opts.Scores[b] {
+				return opts.Scores[a] > opts.Scores[b]
+			}
+			if deg[a] != deg[b] {
+				return deg[a] > deg[b]
+			}
+			return a < b
+		})
+		dropped = len(ids) - opts.MaxNodes
+		for _, id := range ids[opts.MaxNodes:] {
+			delete(keep, id)
+		}
+	}
+
+	return keep, dropped
+}
+
+// nodeLabels returns the display name of every node.
+func nodeLabels(g AstraGraph) map[string]string {
+	labels := map[string]string{}
+	for _, n := range g.Artifacts {
+		labels[n.ID] = firstNonEmpty(n.Name, n.ID)
+	}
+	for _, n := range g.Steps {
+		labels[n.ID] = firstNonEmpty(n.Command, n.ID)
+	}
+	for _, n := range g.Principals {
+		labels[n.ID] = firstNonEmpty(n.Name, n.ID)
+	}
+	for _, n := range g.Resources {
+		labels[n.ID] = n.ID
+	}
+	return labels
+}
+
+// quote makes s a DOT quoted string: backslashes and quotes are escaped,
+// newlines become DOT line breaks.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func truncate(s string, n int) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i] // first line of commit messages
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func riskColor(s float64) string {
+	switch {
+	case s >= 75:
+		return "tomato"
+	case s >= 50:
+		return "orange"
+	case s >= 25:
+		return "khaki1"
+	default:
+		return "palegreen"
+	}
+}
+
+func typeIn(t string, want []string) bool {
+	for _, w := range want {
+		if MatchType(t, strings.TrimSpace(w)) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
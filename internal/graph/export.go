@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Attribute value types shared by the GraphML, GEXF and Neo4j exporters.
+const (
+	attrString  = "string"
+	attrLong    = "long"
+	attrDouble  = "double"
+	attrBoolean = "boolean"
+)
+
+// flatNode is a node with every field as a named attribute: struct fields
+// under their JSON names, environment entries as "env.<key>", metadata as
+// "metadata.<key>". node_type and label are always set.
+type flatNode struct {
+	ID    string
+	Attrs map[string]string
+}
+
+type flatEdge struct {
+	Source, Target, Relation string
+	Attrs                    map[string]string
+}
+
+/*
+flatten prepares g for the attribute-table exporters. Edge endpoints that
+are not nodes become node_type "Unknown" nodes, since graph tools reject
+dangling edges. Nodes come out in ID order, edges in input order.
+*/
+func flatten(g AstraGraph) ([]flatNode, []flatEdge) {
+	ix := NewIndex(g)
+	labels := nodeLabels(g)
+	attrs := map[string]map[string]string{}
+	put := func(id string, kv ...string) map[string]string {
+		m := map[string]string{}
+		for i := 0; i+1 < len(kv); i += 2 {
+			if kv[i+1] != "" {
+				m[kv[i]] = kv[i+1]
+			}
+		}
+		attrs[id] = m
+		return m
+	}
+	for _, n := range g.Artifacts {
+		m := put(n.ID, "kind", n.Kind, "name", n.Name, "namespace", n.Namespace, "version", n.Version, "purl", n.PURL, "hash", n.Hash)
+		if n.Size != 0 {
+			m["size"] = strconv.FormatInt(n.Size, 10)
+		}
+		prefixed(m, "metadata.", n.Metadata)
+	}
+	for _, n := range g.Steps {
+		m := put(n.ID, "command", n.Command, "timestamp", n.Timestamp, "architecture", n.Arch)
+		prefixed(m, "env.", n.Environment)
+		prefixed(m, "metadata.", n.Metadata)
+	}
+	for _, n := range g.Principals {
+		m := put(n.ID, "name", n.Name, "trust_level", n.Trust, "trust_reason", n.TrustReason, "builder", n.Builder)
+		prefixed(m, "metadata.", n.Metadata)
+	}
+	for _, n := range g.Resources {
+		m := put(n.ID, "type", n.Type, "uri", n.URI, "format", n.Format, "purl", n.PURL)
+		prefixed(m, "metadata.", n.Metadata)
+	}
+
+	nodes := make([]flatNode, 0, len(ix.IDs))
+	for _, id := range ix.IDs {
+		m := attrs[id]
+		if m == nil {
+			m = map[string]string{}
+		}
+		m["node_type"] = ix.Types[id]
+		m["label"] = firstNonEmpty(labels[id], id)
+		nodes = append(nodes, flatNode{ID: id, Attrs: m})
+	}
+	edges := make([]flatEdge, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		m := map[string]string{}
+		prefixed(m, "metadata.", e.Metadata)
+		edges = append(edges, flatEdge{Source: e.Source, Target: e.Target, Relation: e.Relation, Attrs: m})
+	}
+	return nodes, edges
+}
+
+func prefixed(dst map[string]string, prefix string, src map[string]string) {
+	for k, v := range src {
+		if v != "" {
+			dst[prefix+k] = v
+		}
+	}
+}
+
+// attrKey is one column of an attribute table.
+type attrKey struct {
+	Name string
+	Type string
+}
+
+/*
+attrKeys returns every attribute name used in recs with its inferred type,
+node_type and label first, the rest sorted. A metadata or environment key is
+long, double or boolean when every value is one in canonical form ("7",
+"0.5", "true"), so versions like "1.10" and hashes like "0042" stay strings
+and the export is lossless. size is always long, other fields strings.
+*/
+func attrKeys(recs []map[string]string) []attrKey {
+	types := map[string]string{}
+	for _, r := range recs {
+		for k, v := range r {
+			t, seen := types[k]
+			switch {
+			case k == "size":
+				types[k] = attrLong
+			case !isExtra(k):
+				types[k] = attrString
+			case !seen:
+				types[k] = valueType(v)
+			case t != attrString && valueType(v) != t:
+				if (t == attrLong || t == attrDouble) && (valueType(v) == attrLong || valueType(v) == attrDouble) {
+					types[k] = attrDouble
+				} else {
+					types[k] = attrString
+				}
+			}
+		}
+	}
+	var out []attrKey
+	for _, k := range []string{"node_type", "label"} {
+		if t, ok := types[k]; ok {
+			out = append(out, attrKey{k, t})
+			delete(types, k)
+		}
+	}
+	var rest []string
+	for k := range types {
+		rest = append(rest, k)
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		out = append(out, attrKey{k, types[k]})
+	}
+	return out
+}
+
+func isExtra(k string) bool {
+	return len(k) > 4 && k[:4] == "env." || len(k) > 9 && k[:9] == "metadata."
+}
+
+func valueType(v string) string {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(i, 10) == v {
+		return attrLong
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == v {
+		return attrDouble
+	}
+	if v == "true" || v == "false" {
+		return attrBoolean
+	}
+	return attrString
+}
+
+func nodeAttrs(nodes []flatNode) []map[string]string {
+	out := make([]map[string]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Attrs
+	}
+	return out
+}
+
+func edgeAttrs(edges []flatEdge) []map[string]string {
+	out := make([]map[string]string, len(edges))
+	for i, e := range edges {
+		out[i] = e.Attrs
+	}
+	return out
+}
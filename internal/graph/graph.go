@@ -1,35 +1,61 @@
 package graph
 
 import (
-	"fmt"
+	"encoding/json"
 	"strings"
 )
 
+/*
+SchemaVersion is the version of the AstraGraph JSON format written by this
+build. Files without schema_version predate versioning and count as 0;
+internal/schema migrates older files and validates them on load. Bump it
+with a migration whenever the JSON form of these types changes.
+
+Version 1: schema_version added; Step.timestamp is RFC 3339 in UTC or empty
+(it used to hold whatever the parser saw, often unix seconds).
+*/
+const SchemaVersion = 1
+
 type Artifact struct {
-	ID       string            `json:"id"`
-	Kind     string            `json:"kind"`
-	Name     string            `json:"name"`
-	Version  string            `json:"version"`
-	Hash     string            `json:"hash,omitempty"`
-	Size     int64             `json:"size,omitempty"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	ID        string            `json:"id"`
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Version   string            `json:"version"`
+	PURL      string            `json:"purl,omitempty"`
+	Hash      string            `json:"hash,omitempty"`
+	Size      int64             `json:"size,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
 type Step struct {
 	ID          string            `json:"id"`
 	Command     string            `json:"command"`
-	Timestamp   string            `json:"timestamp"`
+	Timestamp   string            `json:"timestamp" jsonschema:"format=date-time,empty"`
 	Arch        string            `json:"architecture"`
 	Environment map[string]string `json:"environment"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 }
 
 type Principal struct {
-	ID       string            `json:"id"`
-	Name     string            `json:"name"`
-	Trust    string            `json:"trust_level"`
-	Builder  string            `json:"builder"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Trust       string            `json:"trust_level"`
+	TrustReason string            `json:"trust_reason,omitempty"`
+	Builder     string            `json:"builder"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// SigningKey returns the signing key ID recorded in a principal's or
+// commit artifact's metadata: "pgp_key_id" (buildinfo) or "signing_key"
+// (git). "" when there is none.
+func SigningKey(md map[string]string) string {
+	for _, k := range []string{"pgp_key_id", "signing_key"} {
+		if v := strings.TrimSpace(md[k]); v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 type Resource struct {
@@ -37,61 +63,34 @@ type Resource struct {
 	Type     string            `json:"type"`
 	URI      string            `json:"uri"`
 	Format   string            `json:"format"`
+	PURL     string            `json:"purl,omitempty"`
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type Edge struct {
-	Source   string `json:"source"`
-	Target   string `json:"target"`
-	Relation string `json:"relation"`
+	Source   string            `json:"source"`
+	Target   string            `json:"target"`
+	Relation string            `json:"relation"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type AstraGraph struct {
-	Artifacts  []Artifact  `json:"artifacts"`
-	Steps      []Step      `json:"steps"`
-	Principals []Principal `json:"principals"`
-	Resources  []Resource  `json:"resources"`
-	Edges      []Edge      `json:"edges"`
+	SchemaVersion int         `json:"schema_version"`
+	Artifacts     []Artifact  `json:"artifacts"`
+	Steps         []Step      `json:"steps"`
+	Principals    []Principal `json:"principals"`
+	Resources     []Resource  `json:"resources"`
+	Edges         []Edge      `json:"edges"`
 }
 
-// To DOT renders an AstraGraph into Graphviz DOT format.
-func ToDOT(g AstraGraph) string {
-	var b strings.Builder
-	b.WriteString("digraph astra {\n")
-
-	// Artifacts
-	for _, n := range g.Artifacts {
-		b.WriteString(fmt.Sprintf(
-			"  \"%s\" [label=\"%s\\n(Artifact)\" shape=box];\n",
-			n.ID, n.Name))
-	}
-	// Steps
-	for _, n := range g.Steps {
-		b.WriteString(fmt.Sprintf(
-			"  \"%s\" [label=\"%s\\n(Step)\" shape=diamond];\n",
-			n.ID, n.Command))
-	}
-	// Principals
-	for _, n := range g.Principals {
-		b.WriteString(fmt.Sprintf(
-			"  \"%s\" [label=\"%s\\n(Principal)\" shape=oval];\n",
-			n.ID, n.ID))
-	}
-	// Resources
-	for _, n := range g.Resources {
-		b.WriteString(fmt.Sprintf(
-			"  \"%s\" [label=\"%s\\n(Resource)\" shape=hexagon];\n",
-			n.ID, n.ID))
-	}
-
-	// Edges
-	for _, e := range g.Edges {
-		b.WriteString(fmt.Sprintf(
-			"  \"%s\" -> \"%s\" [label=\"%s\"];\n",
-			e.Source, e.Target, e.Relation))
+// MarshalJSON stamps graphs built in memory (SchemaVersion 0) with the
+// current SchemaVersion, so every file astra writes says what it is.
+func (g AstraGraph) MarshalJSON() ([]byte, error) {
+	type plain AstraGraph
+	if g.SchemaVersion == 0 {
+		g.SchemaVersion = SchemaVersion
 	}
-	b.WriteString("}\n")
-	return b.String()
+	return json.Marshal(plain(g))
 }
 
 //TODO
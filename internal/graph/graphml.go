@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+type graphmlDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+/*
+ToGraphML renders g as GraphML for yEd, Gephi or networkx. Every field and
+metadata entry becomes a typed <key> (see attrKeys); edges carry their
+relation as "relation".
+*/
+func ToGraphML(g AstraGraph) ([]byte, error) {
+	nodes, edges := flatten(g)
+	doc := graphmlDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphmlGraph{ID: "astra", EdgeDefault: "directed"},
+	}
+
+	nkeys := attrKeys(nodeAttrs(nodes))
+	ekeys := append([]attrKey{{"relation", attrString}}, attrKeys(edgeAttrs(edges))...)
+	for i, k := range nkeys {
+		doc.Keys = append(doc.Keys, graphmlKey{ID: "n" + strconv.Itoa(i), For: "node", Name: k.Name, Type: k.Type})
+	}
+	for i, k := range ekeys {
+		doc.Keys = append(doc.Keys, graphmlKey{ID: "e" + strconv.Itoa(i), For: "edge", Name: k.Name, Type: k.Type})
+	}
+
+	for _, n := range nodes {
+		gn := graphmlNode{ID: n.ID}
+		for i, k := range nkeys {
+			if v, ok := n.Attrs[k.Name]; ok {
+				gn.Data = append(gn.Data, graphmlData{Key: "n" + strconv.Itoa(i), Value: v})
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+	for j, e := range edges {
+		ge := graphmlEdge{ID: "edge" + strconv.Itoa(j), Source: e.Source, Target: e.Target}
+		ge.Data = append(ge.Data, graphmlData{Key: "e0", Value: e.Relation})
+		for i, k := range ekeys[1:] {
+			if v, ok := e.Attrs[k.Name]; ok {
+				ge.Data = append(ge.Data, graphmlData{Key: "e" + strconv.Itoa(i+1), Value: v})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode graphml: %w", err)
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
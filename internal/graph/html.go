@@ -0,0 +1,105 @@
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed viewer.html
+var viewerHTML string
+
+const viewerMarker = "/*ASTRA_DATA*/null"
+
+type htmlNode struct {
+	ID    string   `json:"id"`
+	Type  string   `json:"type"`
+	Label string   `json:"label"`
+	Group string   `json:"group,omitempty"`
+	Trust string   `json:"trust,omitempty"`
+	Score *float64 `json:"score,omitempty"`
+	Data  any      `json:"data"`
+}
+
+type htmlData struct {
+	Title   string     `json:"title"`
+	Note    string     `json:"note,omitempty"`
+	RankDir string     `json:"rankDir"`
+	ColorBy string     `json:"colorBy,omitempty"`
+	Nodes   []htmlNode `json:"nodes"`
+	Edges   []Edge     `json:"edges"`
+}
+
+/*
+ToHTML renders g as a single offline HTML page: the viewer script and the
+graph data are embedded, nothing is fetched. The page offers a layered
+(RankDir) or force-directed layout with pan and zoom, a metadata panel per
+node, search by ID or name, type and relation filters, and highlighting of
+everything upstream or downstream of a node, or of the paths between two.
+
+Types, MaxNodes, Clusters (shown as the node's group) and ColorBy/Scores
+apply as in ToDOTWith. Labels are left whole; the page shortens them.
+*/
+func ToHTML(g AstraGraph, title string, opts DOTOptions) (string, error) {
+	ix := NewIndex(g)
+	keep, dropped := selectNodes(g, ix, opts)
+	labels := nodeLabels(g)
+
+	data := map[string]any{}
+	trust := map[string]string{}
+	for _, n := range g.Artifacts {
+		data[n.ID] = n
+	}
+	for _, n := range g.Steps {
+		data[n.ID] = n
+	}
+	for _, n := range g.Principals {
+		data[n.ID] = n
+		trust[n.ID] = n.Trust
+	}
+	for _, n := range g.Resources {
+		data[n.ID] = n
+	}
+
+	d := htmlData{
+		Title:   firstNonEmpty(title, "AStRA graph"),
+		RankDir: strings.ToUpper(firstNonEmpty(opts.RankDir, "TB")),
+		ColorBy: opts.ColorBy,
+		Nodes:   []htmlNode{},
+		Edges:   []Edge{},
+	}
+	if dropped > 0 {
+		d.Note = fmt.Sprintf("%d of %d nodes shown", len(keep), len(keep)+dropped)
+	}
+	for _, id := range ix.IDs {
+		if !keep[id] {
+			continue
+		}
+		n := htmlNode{
+			ID:    id,
+			Type:  ix.Types[id],
+			Label: firstNonEmpty(labels[id], id),
+			Group: opts.Clusters[id],
+			Trust: trust[id],
+			Data:  data[id],
+		}
+		if s, ok := opts.Scores[id]; ok && opts.ColorBy == "risk" {
+			n.Score = &s
+		}
+		d.Nodes = append(d.Nodes, n)
+	}
+	for _, e := range g.Edges {
+		if keep[e.Source] && keep[e.Target] {
+			d.Edges = append(d.Edges, e)
+		}
+	}
+
+	// json.Marshal escapes <, > and &, so no string in the graph can close
+	// the script element.
+	js, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("encode viewer data: %w", err)
+	}
+	return strings.Replace(viewerHTML, viewerMarker, string(js), 1), nil
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Node types as used on the command line (--paths-from Principal ...).
+const (
+	TypeArtifact  = "Artifact"
+	TypeStep      = "Step"
+	TypePrincipal = "Principal"
+	TypeResource  = "Resource"
+	TypeUnknown   = "Unknown" // edge endpoint without a node
+)
+
+// Index is a read-only adjacency view of an AstraGraph.
+// Out/In follow the stored edge direction; FlowOut/FlowIn follow causality
+// (see FlowEdge).
+type Index struct {
+	IDs     []string // sorted
+	Types   map[string]string
+	Out     map[string][]Edge
+	In      map[string][]Edge
+	FlowOut map[string][]string
+	FlowIn  map[string][]string
+}
+
+// NewIndex builds the adjacency view. Neighbour lists are sorted so that
+// every traversal over the index is deterministic.
+func NewIndex(g AstraGraph) *Index {
+	ix := &Index{
+		Types:   map[string]string{},
+		Out:     map[string][]Edge{},
+		In:      map[string][]Edge{},
+		FlowOut: map[string][]string{},
+		FlowIn:  map[string][]string{},
+	}
+	for _, n := range g.Artifacts {
+		ix.Types[n.ID] = TypeArtifact
+	}
+	for _, n := range g.Steps {
+		ix.Types[n.ID] = TypeStep
+	}
+	for _, n := range g.Principals {
+		ix.Types[n.ID] = TypePrincipal
+	}
+	for _, n := range g.Resources {
+		ix.Types[n.ID] = TypeResource
+	}
+
+	seenFlow := map[[2]string]bool{}
+	for _, e := range g.Edges {
+		for _, id := range []string{e.Source, e.Target} {
+			if _, ok := ix.Types[id]; !ok {
+				ix.Types[id] = TypeUnknown
+			}
+		}
+		ix.Out[e.Source] = append(ix.Out[e.Source], e)
+		ix.In[e.Target] = append(ix.In[e.Target], e)
+
+		from, to := FlowEdge(e)
+		if from == to || seenFlow[[2]string{from, to}] {
+			continue
+		}
+		seenFlow[[2]string{from, to}] = true
+		ix.FlowOut[from] = append(ix.FlowOut[from], to)
+		ix.FlowIn[to] = append(ix.FlowIn[to], from)
+	}
+
+	for id := range ix.Types {
+		ix.IDs = append(ix.IDs, id)
+	}
+	sort.Strings(ix.IDs)
+	for _, l := range ix.FlowOut {
+		sort.Strings(l)
+	}
+	for _, l := range ix.FlowIn {
+		sort.Strings(l)
+	}
+	return ix
+}
+
+/*
+FlowEdge orients an edge along the direction of influence:
+
+	principal -> resource -> step -> produced artifact
+	consumed artifact -> step
+
+Edges are stored as step --consumes--> artifact, so consumes is the only
+relation that gets reversed.
+*/
+func FlowEdge(e Edge) (from, to string) {
+	if e.Relation == "consumes" {
+		return e.Target, e.Source
+	}
+	return e.Source, e.Target
+}
+
+// MatchType compares node types case-insensitively ("principal" == "Principal").
+func MatchType(nodeType, want string) bool {
+	return strings.EqualFold(nodeType, want)
+}
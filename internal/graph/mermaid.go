@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mermaidShapes maps node types to Mermaid flowchart shape delimiters.
+var mermaidShapes = map[string][2]string{
+	TypeArtifact:  {"[", "]"},
+	TypeStep:      {"{", "}"},
+	TypePrincipal: {"([", "])"},
+	TypeResource:  {"{{", "}}"},
+}
+
+var mermaidArrows = map[string]string{
+	"consumes":     "-.->",
+	"uses":         "-.->",
+	"carries_out":  "-.->",
+	"performs":     "==>",
+	"same_as":      "<-.->",
+	"differs_from": "==>",
+}
+
+/*
+ToMermaid renders d as a Mermaid flowchart, which GitHub, GitLab and most
+wikis draw inside a ```mermaid block. Nodes are aliased n0, n1... and every
+label is quoted with Mermaid's entity escapes, so IDs with quotes, pipes,
+brackets or HTML cannot break the diagram.
+*/
+func ToMermaid(d Diagram) string {
+	alias, groups, members := diagramIDs(d)
+	var b strings.Builder
+	dir := strings.ToUpper(d.Direction)
+	if dir == "" {
+		dir = "TB"
+	}
+	fmt.Fprintf(&b, "flowchart %s\n", dir)
+	if d.Note != "" {
+		fmt.Fprintf(&b, "  %%%% %s\n", d.Note)
+	}
+
+	node := func(indent string, n DiagramNode) {
+		sh, ok := mermaidShapes[n.Type]
+		if !ok {
+			sh = [2]string{"(", ")"}
+		}
+		fmt.Fprintf(&b, "%s%s%s%s%s\n", indent, alias[n.ID], sh[0], mermaidText(n.Label), sh[1])
+	}
+	for _, n := range members[""] {
+		node("  ", n)
+	}
+	for i, grp := range groups {
+		fmt.Fprintf(&b, "  subgraph c%d[%s]\n", i, mermaidText(grp))
+		for _, n := range members[grp] {
+			node("    ", n)
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, e := range d.Edges {
+		s, ok1 := alias[e.Source]
+		t, ok2 := alias[e.Target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		arrow, ok := mermaidArrows[e.Relation]
+		if !ok {
+			arrow = "-->"
+		}
+		if e.Label == "" {
+			fmt.Fprintf(&b, "  %s %s %s\n", s, arrow, t)
+		} else {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", s, arrow, mermaidText(e.Label), t)
+		}
+	}
+	for _, n := range d.Nodes {
+		if n.Fill != "" {
+			fmt.Fprintf(&b, "  style %s fill:%s\n", alias[n.ID], n.Fill)
+		}
+	}
+	return b.String()
+}
+
+// mermaidText quotes s as a Mermaid label. Mermaid has no backslash escapes;
+// special characters are written as #entity; codes, newlines as <br>.
+func mermaidText(s string) string {
+	r := strings.NewReplacer(
+		"#", "#35;",
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"&", "#amp;",
+		"`", "#96;",
+		"\r\n", "<br>",
+		"\n", "<br>",
+		"\r", "<br>",
+	)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// neo4jBatch is the number of rows per UNWIND statement in ToCypher.
+const neo4jBatch = 500
+
+/*
+ToCypher renders g as a Cypher script for cypher-shell (cypher-shell -f
+graph.cypher). Every node gets the label AstraNode plus its node type and
+is merged on id, so loading the script twice or loading overlapping graphs
+does not duplicate nodes. Relations become upper-case relationship types
+(produces -> PRODUCES). Properties are typed as in the GraphML export.
+*/
+func ToCypher(g AstraGraph) string {
+	nodes, edges := flatten(g)
+	nkeys := attrKeys(nodeAttrs(nodes))
+	ekeys := attrKeys(edgeAttrs(edges))
+
+	var b strings.Builder
+	b.WriteString("// AStRA graph: cypher-shell -f <this file>\n")
+	b.WriteString("CREATE CONSTRAINT astra_node_id IF NOT EXISTS FOR (n:AstraNode) REQUIRE n.id IS UNIQUE;\n\n")
+
+	byType := map[string][]string{}
+	var types []string
+	for _, n := range nodes {
+		t := n.Attrs["node_type"]
+		if _, ok := byType[t]; !ok {
+			types = append(types, t)
+		}
+		byType[t] = append(byType[t], fmt.Sprintf("{id: %s, props: %s}", cypherString(n.ID), cypherMap(n.Attrs, nkeys)))
+	}
+	for _, t := range types {
+		writeUnwind(&b, byType[t], fmt.Sprintf("MERGE (n:AstraNode {id: row.id}) SET n:%s, n += row.props;\n", cypherName(t)))
+	}
+
+	byRel := map[string][]string{}
+	var rels []string
+	for _, e := range edges {
+		r := relType(e.Relation)
+		if _, ok := byRel[r]; !ok {
+			rels = append(rels, r)
+		}
+		byRel[r] = append(byRel[r], fmt.Sprintf("{s: %s, t: %s, props: %s}", cypherString(e.Source), cypherString(e.Target), cypherMap(e.Attrs, ekeys)))
+	}
+	for _, r := range rels {
+		writeUnwind(&b, byRel[r], fmt.Sprintf(
+			"MATCH (a:AstraNode {id: row.s}) MATCH (b:AstraNode {id: row.t})\nMERGE (a)-[r:%s]->(b) SET r += row.props;\n", cypherName(r)))
+	}
+	return b.String()
+}
+
+func writeUnwind(b *strings.Builder, rows []string, body string) {
+	for i := 0; i < len(rows); i += neo4jBatch {
+		j := min(i+neo4jBatch, len(rows))
+		b.WriteString("UNWIND [\n  ")
+		b.WriteString(strings.Join(rows[i:j], ",\n  "))
+		b.WriteString("\n] AS row\n")
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+}
+
+// cypherMap writes the attributes present in attrs as a Cypher map literal
+// with typed values.
+func cypherMap(attrs map[string]string, keys []attrKey) string {
+	var parts []string
+	for _, k := range keys {
+		v, ok := attrs[k.Name]
+		if !ok {
+			continue
+		}
+		if k.Type == attrString {
+			v = cypherString(v)
+		}
+		parts = append(parts, cypherName(k.Name)+": "+v)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func cypherString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// cypherName quotes a label, relationship type or property key.
+func cypherName(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// relType turns a relation into a relationship type: PRODUCES, CARRIES_OUT.
+func relType(rel string) string {
+	if rel == "" {
+		return "RELATED"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, rel)
+}
+
+/*
+ToNeo4jCSV renders g as the node and relationship files of a neo4j-admin
+bulk import:
+
+	neo4j-admin database import full --multiline-fields=true \
+		--nodes=nodes.csv --relationships=edges.csv
+
+Headers carry the property types (name:long ...); every node is labelled
+AstraNode plus its node type, as in ToCypher. Colons in property names are
+replaced by underscores, since the header uses them as type separators.
+*/
+func ToNeo4jCSV(g AstraGraph) (nodesCSV, edgesCSV []byte, err error) {
+	nodes, edges := flatten(g)
+	nkeys := attrKeys(nodeAttrs(nodes))
+	ekeys := attrKeys(edgeAttrs(edges))
+
+	var nb bytes.Buffer
+	w := csv.NewWriter(&nb)
+	header := append([]string{"id:ID", ":LABEL"}, csvHeader(nkeys)...)
+	if err := w.Write(header); err != nil {
+		return nil, nil, fmt.Errorf("write nodes.csv: %w", err)
+	}
+	for _, n := range nodes {
+		row := []string{n.ID, "AstraNode;" + n.Attrs["node_type"]}
+		for _, k := range nkeys {
+			row = append(row, n.Attrs[k.Name])
+		}
+		if err := w.Write(row); err != nil {
+			return nil, nil, fmt.Errorf("write nodes.csv: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, nil, fmt.Errorf("write nodes.csv: %w", err)
+	}
+
+	var eb bytes.Buffer
+	w = csv.NewWriter(&eb)
+	header = append([]string{":START_ID", ":END_ID", ":TYPE"}, csvHeader(ekeys)...)
+	if err := w.Write(header); err != nil {
+		return nil, nil, fmt.Errorf("write edges.csv: %w", err)
+	}
+	for _, e := range edges {
+		row := []string{e.Source, e.Target, relType(e.Relation)}
+		for _, k := range ekeys {
+			row = append(row, e.Attrs[k.Name])
+		}
+		if err := w.Write(row); err != nil {
+			return nil, nil, fmt.Errorf("write edges.csv: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, nil, fmt.Errorf("write edges.csv: %w", err)
+	}
+	return nb.Bytes(), eb.Bytes(), nil
+}
+
+func csvHeader(keys []attrKey) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = strings.ReplaceAll(k.Name, ":", "_") + ":" + k.Type
+	}
+	return out
+}
@@ -0,0 +1,20 @@
+package graph
+
+import (
+	"path"
+	"strings"
+)
+
+// DirPrefix returns the first depth directories of a file path ("." for
+// files at the repository root).
+func DirPrefix(p string, depth int) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return "."
+	}
+	segs := strings.Split(dir, "/")
+	if len(segs) > depth {
+		segs = segs[:depth]
+	}
+	return strings.Join(segs, "/")
+}
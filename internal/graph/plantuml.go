@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// plantumlElements maps node types to PlantUML deployment elements.
+var plantumlElements = map[string]string{
+	TypeArtifact:  "artifact",
+	TypeStep:      "rectangle",
+	TypePrincipal: "actor",
+	TypeResource:  "node",
+}
+
+var plantumlArrows = map[string]string{
+	"consumes":     "..>",
+	"uses":         "..>",
+	"carries_out":  "..>",
+	"performs":     "==>",
+	"same_as":      "<..>",
+	"differs_from": "-[#red]->",
+}
+
+/*
+ToPlantUML renders d as a PlantUML deployment-style diagram. Clusters become
+packages. Nodes are aliased n0, n1...; in labels &, \ and quotes are written
+as HTML entities and newlines as \n, so no ID can end a line or the diagram
+early.
+*/
+func ToPlantUML(d Diagram) string {
+	alias, groups, members := diagramIDs(d)
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+	switch strings.ToUpper(d.Direction) {
+	case "LR", "RL":
+		b.WriteString("left to right direction\n")
+	default:
+		b.WriteString("top to bottom direction\n")
+	}
+	if d.Note != "" {
+		fmt.Fprintf(&b, "caption %s\n", plantumlEscape(d.Note))
+	}
+
+	node := func(indent string, n DiagramNode) {
+		el, ok := plantumlElements[n.Type]
+		if !ok {
+			el = "card"
+		}
+		fill := ""
+		if n.Fill != "" {
+			fill = " " + n.Fill
+		}
+		fmt.Fprintf(&b, "%s%s \"%s\" as %s%s\n", indent, el, plantumlEscape(n.Label), alias[n.ID], fill)
+	}
+	for _, n := range members[""] {
+		node("", n)
+	}
+	for _, grp := range groups {
+		fmt.Fprintf(&b, "package \"%s\" {\n", plantumlEscape(grp))
+		for _, n := range members[grp] {
+			node("  ", n)
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, e := range d.Edges {
+		s, ok1 := alias[e.Source]
+		t, ok2 := alias[e.Target]
+		if !ok1 || !ok2 {
+			continue
+		}
+		arrow, ok := plantumlArrows[e.Relation]
+		if !ok {
+			arrow = "-->"
+		}
+		if e.Label == "" {
+			fmt.Fprintf(&b, "%s %s %s\n", s, arrow, t)
+		} else {
+			fmt.Fprintf(&b, "%s %s %s : %s\n", s, arrow, t, plantumlEscape(e.Label))
+		}
+	}
+	b.WriteString("@enduml\n")
+	return b.String()
+}
+
+func plantumlEscape(s string) string {
+	r := strings.NewReplacer(
+		"&", "&#38;",
+		`\`, "&#92;",
+		`"`, "&#34;",
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
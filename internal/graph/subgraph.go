@@ -0,0 +1,33 @@
+package graph
+
+// Subgraph returns the nodes of g whose ID is in keep, and the edges
+// between them for which edge returns true (nil keeps every such edge).
+func Subgraph(g AstraGraph, keep map[string]bool, edge func(Edge) bool) AstraGraph {
+	var out AstraGraph
+	for _, n := range g.Artifacts {
+		if keep[n.ID] {
+			out.Artifacts = append(out.Artifacts, n)
+		}
+	}
+	for _, n := range g.Steps {
+		if keep[n.ID] {
+			out.Steps = append(out.Steps, n)
+		}
+	}
+	for _, n := range g.Principals {
+		if keep[n.ID] {
+			out.Principals = append(out.Principals, n)
+		}
+	}
+	for _, n := range g.Resources {
+		if keep[n.ID] {
+			out.Resources = append(out.Resources, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if keep[e.Source] && keep[e.Target] && (edge == nil || edge(e)) {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
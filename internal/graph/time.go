@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the timestamp formats our parsers emit besides unix seconds
+// (buildinfo Build-Date is RFC 2822, SLSA/in-toto use RFC 3339).
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, _2 Jan 2006 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02",
+}
+
+// ParseTimestamp interprets the string timestamps stored in Step.Timestamp
+// and metadata ("time", "first_seen" ...). ok is false for empty or
+// unrecognised values.
+func ParseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), true
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NormalizeTimestamp rewrites a timestamp ParseTimestamp understands as
+// RFC 3339 in UTC, the Step.timestamp form since schema version 1,
+// keeping fractional seconds. ok is false, and s is returned as is, for
+// empty or unrecognised values.
+func NormalizeTimestamp(s string) (string, bool) {
+	t, ok := ParseTimestamp(s)
+	if !ok {
+		return s, false
+	}
+	return t.Format(time.RFC3339Nano), true
+}
+
+// SetTimestamp stores raw as the step time in its schema version 1 form.
+// A value ParseTimestamp cannot read leaves Timestamp empty and is kept in
+// Metadata["timestamp"] instead, as the version 1 migration does.
+func (s *Step) SetTimestamp(raw string) {
+	ts, ok := NormalizeTimestamp(raw)
+	if ok {
+		s.Timestamp = ts
+		return
+	}
+	s.Timestamp = ""
+	if strings.TrimSpace(raw) == "" {
+		return
+	}
+	if s.Metadata == nil {
+		s.Metadata = map[string]string{}
+	}
+	if _, exists := s.Metadata["timestamp"]; !exists {
+		s.Metadata["timestamp"] = raw
+	}
+}
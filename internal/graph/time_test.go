@@ -0,0 +1,38 @@
+package graph
+
+import "testing"
+
+func TestNormalizeTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		ok       bool
+	}{
+		{"1700000000", "2023-11-14T22:13:20Z", true},
+		{"2024-01-01T12:00:00+02:00", "2024-01-01T10:00:00Z", true},
+		{"2024-01-01T12:00:00.5Z", "2024-01-01T12:00:00.5Z", true},
+		{"2024-01-01T12:00:00.123456789-01:00", "2024-01-01T13:00:00.123456789Z", true},
+		{"Mon, 01 Jan 2024 12:00:00 +0100", "2024-01-01T11:00:00Z", true},
+		{"Mon,  1 Jan 2024 12:00:00 +0000", "2024-01-01T12:00:00Z", true},
+		{"2024-01-01", "2024-01-01T00:00:00Z", true},
+		{"yesterday", "yesterday", false},
+		{"", "", false},
+	} {
+		got, ok := NormalizeTimestamp(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("NormalizeTimestamp(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSetTimestampKeepsRaw(t *testing.T) {
+	s := Step{Metadata: map[string]string{}}
+	s.SetTimestamp("not a time")
+	if s.Timestamp != "" || s.Metadata["timestamp"] != "not a time" {
+		t.Errorf("step %+v", s)
+	}
+	s = Step{Metadata: map[string]string{"timestamp": "original"}}
+	s.SetTimestamp("not a time")
+	if s.Metadata["timestamp"] != "original" {
+		t.Errorf("overwrote metadata timestamp: %+v", s)
+	}
+}
@@ -0,0 +1,384 @@
+package importer
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+/*
+GUAC converts GUAC GraphQL results into an AstraGraph. Any JSON holding
+objects with a __typename is accepted: a query response ({"data": ...}),
+a node list, or the output of a neighbors/path query. Nouns become nodes:
+
+	Package   artifact, ID = purl (one per version in the trie)
+	Source    artifact, artifact:gitcommit:<namespace>/<name>@<commit> when
+	          a commit is known, as the git parser emits, else
+	          artifact:source:<type>/<namespace>/<name>[@<tag>]
+	Artifact  artifact, ID = artifact:<algorithm>:<digest>
+	Builder   principal, ID = principal:builder:<uri>, metadata builder_id
+
+Evidence becomes structure or metadata:
+
+	HasSLSA          step (builder performs, consumes builtFrom, produces subject)
+	IsDependency     package's dependency step consumes the dependency
+	HasSourceAt      package's dependency step consumes the source
+	IsOccurrence,
+	HashEqual,
+	PkgEqual         same_as edges
+	CertifyVuln      "vulnerabilities" on the package
+	CertifyBad/Good,
+	HasMetadata,
+	CertifyScorecard,
+	HasSBOM          metadata on the subject
+
+Every GUAC field not mapped to a struct field is kept as metadata (nested
+values as JSON), and each node records its GUAC id as guac_id.
+*/
+func GUAC(data []byte) (graph.AstraGraph, []string, error) {
+	root, err := decode(data)
+	if err != nil {
+		return graph.AstraGraph{}, nil, fmt.Errorf("parse guac json: %w", err)
+	}
+	var objs []map[string]any
+	collectTyped(root, &objs)
+	if len(objs) == 0 {
+		return graph.AstraGraph{}, nil, fmt.Errorf("no GUAC nodes (objects with __typename) found")
+	}
+
+	gb := &guacBuilder{builder: newBuilder()}
+	// nouns first, so evidence can annotate them
+	for _, o := range objs {
+		switch str(o, "__typename") {
+		case "Package", "Source", "Artifact", "Builder":
+			gb.noun(o)
+		}
+	}
+	for _, o := range objs {
+		gb.evidence(o)
+	}
+	g, ws := gb.result()
+	return g, ws, nil
+}
+
+// collectTyped gathers the outermost objects carrying a __typename, in
+// document order for arrays and key order for objects, so the first node
+// of an ID to be added wins the same way on every run.
+func collectTyped(v any, out *[]map[string]any) {
+	switch x := v.(type) {
+	case map[string]any:
+		if _, ok := x["__typename"]; ok {
+			*out = append(*out, x)
+			return
+		}
+		for _, k := range slices.Sorted(maps.Keys(x)) {
+			collectTyped(x[k], out)
+		}
+	case []any:
+		for _, c := range x {
+			collectTyped(c, out)
+		}
+	}
+}
+
+type guacBuilder struct {
+	*builder
+}
+
+// noun adds a Package, Source, Artifact or Builder and returns the IDs of
+// the nodes it stands for (a package trie can hold several versions).
+func (gb *guacBuilder) noun(o map[string]any) []string {
+	switch nounType(o) {
+	case "Package":
+		return gb.pkg(o)
+	case "Source":
+		return gb.src(o)
+	case "Artifact":
+		return []string{gb.art(o)}
+	case "Builder":
+		return []string{gb.builderNode(o)}
+	}
+	return nil
+}
+
+func (gb *guacBuilder) pkg(o map[string]any) []string {
+	var ids []string
+	typ := str(o, "type")
+	for _, ns := range objects(o["namespaces"]) {
+		for _, nm := range objects(ns["names"]) {
+			base := mapper.Identity{Type: typ, Namespace: str(ns, "namespace"), Name: str(nm, "name")}
+			versions := objects(nm["versions"])
+			if len(versions) == 0 {
+				id := base.PURL()
+				gb.artifact(graph.Artifact{ID: id, Kind: "package", Name: base.Name, Namespace: base.Namespace, PURL: id,
+					Metadata: withID(flat(nm, "id", "name", "versions"), nm)})
+				ids = append(ids, id)
+				continue
+			}
+			for _, v := range versions {
+				ident := base
+				ident.Version = str(v, "version")
+				ident.Subpath = str(v, "subpath")
+				for _, q := range objects(v["qualifiers"]) {
+					if ident.Qualifiers == nil {
+						ident.Qualifiers = map[string]string{}
+					}
+					ident.Qualifiers[str(q, "key")] = str(q, "value")
+				}
+				id := ident.PURL()
+				md := withID(flat(v, "id", "version", "subpath", "qualifiers"), v)
+				gb.artifact(graph.Artifact{ID: id, Kind: "package", Name: ident.Name, Namespace: ident.Namespace,
+					Version: ident.Version, PURL: id, Metadata: md})
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func (gb *guacBuilder) src(o map[string]any) []string {
+	var ids []string
+	typ := str(o, "type")
+	for _, ns := range objects(o["namespaces"]) {
+		for _, nm := range objects(ns["names"]) {
+			slug := strings.Trim(str(ns, "namespace")+"/"+str(nm, "name"), "/")
+			commit, tag := str(nm, "commit"), str(nm, "tag")
+			var id, kind string
+			switch {
+			case commit != "":
+				id, kind = "artifact:gitcommit:"+slug+"@"+commit, "git-commit"
+			case tag != "":
+				id, kind = "artifact:source:"+typ+"/"+slug+"@"+tag, "source"
+			default:
+				id, kind = "artifact:source:"+typ+"/"+slug, "source"
+			}
+			md := withID(flat(nm, "id", "name"), nm)
+			md["source_type"] = typ
+			gb.artifact(graph.Artifact{ID: id, Kind: kind, Name: slug, Version: cmp.Or(commit, tag), Metadata: md})
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (gb *guacBuilder) art(o map[string]any) string {
+	alg, dig := strings.ToLower(str(o, "algorithm")), strings.ToLower(str(o, "digest"))
+	id := "artifact:" + alg + ":" + dig
+	md := withID(flat(o, "id", "algorithm", "digest"), o)
+	gb.artifact(graph.Artifact{ID: id, Kind: "digest", Name: alg + ":" + dig, Hash: alg + ":" + dig, Metadata: md})
+	return id
+}
+
+func (gb *guacBuilder) builderNode(o map[string]any) string {
+	uri := str(o, "uri")
+	id := "principal:builder:" + uri
+	md := withID(flat(o, "id", "uri"), o)
+	md["builder_id"] = uri
+	gb.principal(graph.Principal{ID: id, Name: uri, Builder: uri, Trust: "unknown", Metadata: md})
+	return id
+}
+
+// nounType is the __typename of o, or, for nested objects queried without
+// it, the noun its fields identify.
+func nounType(o map[string]any) string {
+	if t := str(o, "__typename"); t != "" {
+		return t
+	}
+	switch {
+	case o["algorithm"] != nil && o["digest"] != nil:
+		return "Artifact"
+	case o["uri"] != nil:
+		return "Builder"
+	}
+	for _, ns := range objects(o["namespaces"]) {
+		for _, nm := range objects(ns["names"]) {
+			if nm["versions"] != nil {
+				return "Package"
+			}
+			if nm["commit"] != nil || nm["tag"] != nil {
+				return "Source"
+			}
+		}
+	}
+	return ""
+}
+
+// refs resolves a nested noun (subject, package, artifact...) to node IDs.
+func (gb *guacBuilder) refs(v any) []string {
+	if o := object(v); o != nil {
+		return gb.noun(o)
+	}
+	var ids []string
+	for _, o := range objects(v) {
+		ids = append(ids, gb.noun(o)...)
+	}
+	return ids
+}
+
+// withID records the GUAC id of o, when it has one.
+func withID(md map[string]string, o map[string]any) map[string]string {
+	if id := str(o, "id"); id != "" {
+		md["guac_id"] = id
+	}
+	return md
+}
+
+// evidenceMeta is the metadata of an evidence node: its own fields minus
+// the references the importer turns into structure.
+func evidenceMeta(o map[string]any, refs ...string) map[string]string {
+	md := withID(flat(o, append(refs, "id")...), o)
+	md["guac_type"] = str(o, "__typename")
+	return md
+}
+
+func (gb *guacBuilder) evidence(o map[string]any) {
+	typ := str(o, "__typename")
+	switch typ {
+	case "Package", "Source", "Artifact", "Builder":
+		return
+
+	case "HasSLSA":
+		subjects := gb.refs(o["subject"])
+		slsa := object(o["slsa"])
+		if len(subjects) == 0 || slsa == nil {
+			gb.warn("HasSLSA without subject or slsa skipped")
+			return
+		}
+		sid := "step:slsa:" + cmp.Or(str(o, "id"), subjects[0])
+		md := withID(flat(slsa, "builtFrom", "builtBy", "slsaPredicate", "buildType", "startedOn"), o)
+		md["guac_type"] = typ
+		for _, p := range objects(slsa["slsaPredicate"]) {
+			md["slsa."+str(p, "key")] = str(p, "value")
+		}
+		gb.step(graph.Step{ID: sid, Command: str(slsa, "buildType"), Timestamp: str(slsa, "startedOn"), Metadata: md})
+		for _, b := range gb.refs(slsa["builtBy"]) {
+			gb.edge(b, sid, "performs", nil)
+		}
+		for _, in := range gb.refs(slsa["builtFrom"]) {
+			gb.edge(sid, in, "consumes", nil)
+		}
+		for _, s := range subjects {
+			gb.edge(sid, s, "produces", nil)
+		}
+
+	case "IsDependency":
+		dep := o["dependencyPackage"]
+		if dep == nil {
+			dep = o["dependentPackage"] // GUAC before v0.4
+		}
+		md := evidenceMeta(o, "package", "dependencyPackage", "dependentPackage")
+		md["relationship"] = "is_dependency"
+		gb.consumes(typ, gb.refs(o["package"]), gb.refs(dep), md)
+
+	case "HasSourceAt":
+		md := evidenceMeta(o, "package", "source")
+		md["relationship"] = "has_source_at"
+		gb.consumes(typ, gb.refs(o["package"]), gb.refs(o["source"]), md)
+
+	case "IsOccurrence":
+		gb.sameAs(typ, gb.refs(o["subject"]), gb.refs(o["artifact"]), evidenceMeta(o, "subject", "artifact"))
+
+	case "HashEqual", "PkgEqual":
+		field := map[string]string{"HashEqual": "artifacts", "PkgEqual": "packages"}[typ]
+		ids := gb.refs(o[field])
+		if len(ids) < 2 {
+			gb.warn("%s with fewer than two %s skipped", typ, field)
+			return
+		}
+		gb.sameAs(typ, ids[:1], ids[1:], evidenceMeta(o, field))
+
+	case "CertifyVuln":
+		vuln := object(o["vulnerability"])
+		var vids []string
+		for _, v := range objects(vuln["vulnerabilityIDs"]) {
+			if id := str(v, "vulnerabilityID"); id != "" && !strings.EqualFold(str(vuln, "type"), "novuln") {
+				vids = append(vids, id)
+			}
+		}
+		for _, p := range gb.refs(o["package"]) {
+			md := gb.meta(p)
+			for _, id := range vids {
+				appendList(md, "vulnerabilities", id)
+			}
+			if m := object(o["metadata"]); m != nil && len(vids) > 0 {
+				md["vuln_scanner"] = cmp.Or(str(m, "scannerUri"), md["vuln_scanner"])
+				md["vuln_scanned_on"] = cmp.Or(str(m, "timeScanned"), md["vuln_scanned_on"])
+			}
+		}
+
+	case "CertifyBad", "CertifyGood":
+		key := map[string]string{"CertifyBad": "guac_certify_bad", "CertifyGood": "guac_certify_good"}[typ]
+		gb.annotateAll(typ, gb.refs(o["subject"]), map[string]string{key: cmp.Or(str(o, "justification"), "true")})
+
+	case "HasMetadata":
+		gb.annotateAll(typ, gb.refs(o["subject"]), map[string]string{"guac_metadata." + str(o, "key"): str(o, "value")})
+
+	case "HasSBOM":
+		md := map[string]string{}
+		for k, v := range flat(o, "id", "subject") {
+			md["sbom."+k] = v
+		}
+		gb.annotateAll(typ, gb.refs(o["subject"]), md)
+
+	case "CertifyScorecard":
+		sc := object(o["scorecard"])
+		md := map[string]string{"scorecard": str(sc, "aggregateScore")}
+		for k, v := range flat(sc, "aggregateScore") {
+			md["scorecard."+k] = v
+		}
+		gb.annotateAll(typ, gb.refs(o["source"]), md)
+
+	default:
+		gb.warn("unsupported GUAC type %s skipped", typ)
+	}
+}
+
+func (gb *guacBuilder) consumes(typ string, consumers, inputs []string, md map[string]string) {
+	if len(consumers) == 0 || len(inputs) == 0 {
+		gb.warn("%s with a missing endpoint skipped", typ)
+		return
+	}
+	for _, c := range consumers {
+		sid := gb.depStep(c, "guac")
+		for _, in := range inputs {
+			gb.edge(sid, in, "consumes", md)
+		}
+	}
+}
+
+func (gb *guacBuilder) sameAs(typ string, as, bs []string, md map[string]string) {
+	if len(as) == 0 || len(bs) == 0 {
+		gb.warn("%s with a missing endpoint skipped", typ)
+		return
+	}
+	for _, a := range as {
+		for _, b := range bs {
+			if a != b {
+				gb.edge(a, b, "same_as", md)
+			}
+		}
+	}
+}
+
+func (gb *guacBuilder) annotateAll(typ string, ids []string, md map[string]string) {
+	if len(ids) == 0 {
+		gb.warn("%s without a subject skipped", typ)
+		return
+	}
+	for _, id := range ids {
+		if m := gb.meta(id); m != nil {
+			for k, v := range md {
+				m[k] = v
+			}
+			continue
+		}
+		if i, ok := gb.princs[id]; ok { // builders are principals
+			gb.g.Principals[i].Metadata = merge(gb.g.Principals[i].Metadata, md)
+		}
+	}
+}
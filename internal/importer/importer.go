@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Formats accepted by Load.
+const (
+	FormatGUAC = "guac"
+	FormatSPDX = "spdx"
+)
+
+// Load reads a provenance graph from another tool and converts it into an
+// AstraGraph. Warnings list what was skipped.
+func Load(path, format string) (graph.AstraGraph, []string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return graph.AstraGraph{}, nil, err
+	}
+	switch format {
+	case FormatGUAC:
+		return GUAC(b)
+	case FormatSPDX:
+		return SPDX(b)
+	}
+	return graph.AstraGraph{}, nil, fmt.Errorf("unknown import format %q (guac|spdx)", format)
+}
+
+// decode parses JSON keeping numbers as written, so metadata round-trips
+// exactly.
+func decode(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+/*
+flat turns the fields of a source object into metadata: strings, numbers
+and booleans as written, anything nested as compact JSON, so no attribute
+of the original is lost. skip lists fields the importer maps itself.
+*/
+func flat(obj map[string]any, skip ...string) map[string]string {
+	out := map[string]string{}
+	for k, v := range obj {
+		if k == "__typename" || slices.Contains(skip, k) || v == nil {
+			continue
+		}
+		if s := scalar(v); s != "" {
+			out[k] = s
+			continue
+		}
+		if b, err := json.Marshal(v); err == nil {
+			if string(b) != "[]" && string(b) != "{}" {
+				out[k] = string(b)
+			}
+		}
+	}
+	return out
+}
+
+func scalar(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+func str(obj map[string]any, k string) string {
+	return scalar(obj[k])
+}
+
+func object(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func objects(v any) []map[string]any {
+	var out []map[string]any
+	if arr, ok := v.([]any); ok {
+		for _, x := range arr {
+			if m := object(x); m != nil {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+/*
+builder accumulates nodes and edges. A node added twice keeps its first
+fields and gains the metadata keys it did not have; an edge added twice
+(same source, target and relation) is merged the same way.
+*/
+type builder struct {
+	g        graph.AstraGraph
+	arts     map[string]int
+	steps    map[string]int
+	princs   map[string]int
+	edges    map[[3]string]int
+	warnings map[string]int
+}
+
+func newBuilder() *builder {
+	return &builder{
+		arts:     map[string]int{},
+		steps:    map[string]int{},
+		princs:   map[string]int{},
+		edges:    map[[3]string]int{},
+		warnings: map[string]int{},
+	}
+}
+
+func (b *builder) artifact(a graph.Artifact) {
+	if i, ok := b.arts[a.ID]; ok {
+		b.g.Artifacts[i].Metadata = merge(b.g.Artifacts[i].Metadata, a.Metadata)
+		return
+	}
+	b.arts[a.ID] = len(b.g.Artifacts)
+	b.g.Artifacts = append(b.g.Artifacts, a)
+}
+
+func (b *builder) step(s graph.Step) {
+	s.SetTimestamp(s.Timestamp)
+	if i, ok := b.steps[s.ID]; ok {
+		b.g.Steps[i].Metadata = merge(b.g.Steps[i].Metadata, s.Metadata)
+		return
+	}
+	b.steps[s.ID] = len(b.g.Steps)
+	b.g.Steps = append(b.g.Steps, s)
+}
+
+func (b *builder) principal(p graph.Principal) {
+	if i, ok := b.princs[p.ID]; ok {
+		b.g.Principals[i].Metadata = merge(b.g.Principals[i].Metadata, p.Metadata)
+		return
+	}
+	b.princs[p.ID] = len(b.g.Principals)
+	b.g.Principals = append(b.g.Principals, p)
+}
+
+func (b *builder) edge(src, dst, rel string, md map[string]string) {
+	if len(md) == 0 {
+		md = nil
+	}
+	k := [3]string{src, dst, rel}
+	if i, ok := b.edges[k]; ok {
+		b.g.Edges[i].Metadata = merge(b.g.Edges[i].Metadata, md)
+		return
+	}
+	b.edges[k] = len(b.g.Edges)
+	b.g.Edges = append(b.g.Edges, graph.Edge{Source: src, Target: dst, Relation: rel, Metadata: md})
+}
+
+// meta returns the metadata of an artifact already in the graph, for
+// evidence that annotates a node; nil when there is no such artifact.
+func (b *builder) meta(id string) map[string]string {
+	i, ok := b.arts[id]
+	if !ok {
+		return nil
+	}
+	if b.g.Artifacts[i].Metadata == nil {
+		b.g.Artifacts[i].Metadata = map[string]string{}
+	}
+	return b.g.Artifacts[i].Metadata
+}
+
+// depStep is the synthetic step that builds id from its dependencies:
+// imported "A depends on B" becomes step --produces--> A and
+// step --consumes--> B, the shape astra's flow analyses expect.
+func (b *builder) depStep(id, source string) string {
+	sid := "step:deps:" + id
+	b.step(graph.Step{ID: sid, Command: "dependencies", Metadata: map[string]string{"source": source}})
+	b.edge(sid, id, "produces", nil)
+	return sid
+}
+
+func (b *builder) warn(format string, args ...any) {
+	b.warnings[fmt.Sprintf(format, args...)]++
+}
+
+func (b *builder) result() (graph.AstraGraph, []string) {
+	var ws []string
+	for w, n := range b.warnings {
+		if n > 1 {
+			w = fmt.Sprintf("%s (%d times)", w, n)
+		}
+		ws = append(ws, w)
+	}
+	sort.Strings(ws)
+	// meta may have left empty maps behind; drop them so the graph equals
+	// its own JSON round trip
+	for i := range b.g.Artifacts {
+		if len(b.g.Artifacts[i].Metadata) == 0 {
+			b.g.Artifacts[i].Metadata = nil
+		}
+	}
+	return b.g, ws
+}
+
+// merge adds the keys of src missing from dst; existing values win.
+func merge(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = map[string]string{}
+	}
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+// appendList adds v to the comma-separated list under key, keeping it
+// sorted and unique.
+func appendList(md map[string]string, key, v string) {
+	set := map[string]bool{v: true}
+	for _, x := range strings.Split(md[key], ",") {
+		if x != "" {
+			set[x] = true
+		}
+	}
+	var out []string
+	for x := range set {
+		out = append(out, x)
+	}
+	sort.Strings(out)
+	md[key] = strings.Join(out, ",")
+}
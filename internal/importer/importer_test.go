@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGUACDeterministic(t *testing.T) {
+	// two Artifact nodes under sibling keys; the same ID in both, with
+	// different metadata, so the first one added decides
+	in := []byte(`{"data": {
+		"b": {"__typename": "Artifact", "id": "2", "algorithm": "sha256", "digest": "AB", "note": "b"},
+		"a": {"__typename": "Artifact", "id": "1", "algorithm": "sha256", "digest": "ab", "note": "a"},
+		"c": {"__typename": "Artifact", "id": "3", "algorithm": "sha256", "digest": "cd"}
+	}}`)
+	var first []byte
+	for i := 0; i < 20; i++ {
+		g, _, err := GUAC(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(g)
+		if i == 0 {
+			first = b
+			if len(g.Artifacts) != 2 || g.Artifacts[0].Metadata["note"] != "a" {
+				t.Fatalf("artifacts %+v", g.Artifacts)
+			}
+		} else if string(b) != string(first) {
+			t.Fatalf("run %d differs:\n%s\n%s", i, b, first)
+		}
+	}
+}
+
+func TestSPDXNonFlowRelationships(t *testing.T) {
+	g, ws, err := SPDX([]byte(`{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "documentNamespace": "https://e/d",
+		"documentDescribes": ["SPDXRef-a"],
+		"packages": [{"SPDXID": "SPDXRef-a", "name": "a"}, {"SPDXID": "SPDXRef-b", "name": "b"}],
+		"relationships": [
+			{"spdxElementId": "SPDXRef-a", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-b"},
+			{"spdxElementId": "SPDXRef-a", "relationshipType": "OTHER", "relatedSpdxElement": "SPDXRef-b", "comment": "patched copy"}
+		]}`))
+	if err != nil || len(ws) > 0 {
+		t.Fatal(err, ws)
+	}
+	const doc, a, b = "artifact:spdx:https://e/d", "artifact:spdx:https://e/d#SPDXRef-a", "artifact:spdx:https://e/d#SPDXRef-b"
+	for _, e := range g.Edges {
+		if e.Source == doc || e.Target == doc && e.Relation != "produces" {
+			t.Errorf("document on a flow edge: %+v", e)
+		}
+	}
+	md := map[string]map[string]string{}
+	for _, x := range g.Artifacts {
+		md[x.ID] = x.Metadata
+	}
+	if md[doc]["spdx.describes"] != a {
+		t.Errorf("document metadata %v", md[doc])
+	}
+	if md[a]["spdx.other"] != b || md[a]["spdx.other."+b] != `{"comment":"patched copy"}` {
+		t.Errorf("package metadata %v", md[a])
+	}
+}
@@ -0,0 +1,255 @@
+package importer
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+// SPDX 2.x relationship types by their flow of influence. Inward:
+// "A <type> B" means B went into A (A's step consumes B); outward: A went
+// into B.
+var (
+	spdxInward = map[string]bool{
+		"DEPENDS_ON": true, "CONTAINS": true, "GENERATED_FROM": true,
+		"DESCENDANT_OF": true, "HAS_PREREQUISITE": true, "EXPANDED_FROM_ARCHIVE": true,
+		"STATIC_LINK": true, "DYNAMIC_LINK": true,
+	}
+	spdxOutward = map[string]bool{
+		"DEPENDENCY_OF": true, "BUILD_DEPENDENCY_OF": true, "DEV_DEPENDENCY_OF": true,
+		"OPTIONAL_DEPENDENCY_OF": true, "PROVIDED_DEPENDENCY_OF": true, "TEST_DEPENDENCY_OF": true,
+		"RUNTIME_DEPENDENCY_OF": true, "CONTAINED_BY": true, "GENERATES": true, "ANCESTOR_OF": true,
+		"BUILD_TOOL_OF": true, "DEV_TOOL_OF": true, "TEST_TOOL_OF": true, "PREREQUISITE_FOR": true,
+	}
+	spdxSame = map[string]bool{"COPY_OF": true, "VARIANT_OF": true}
+)
+
+/*
+SPDX converts an SPDX 2.x JSON document into an AstraGraph.
+
+Packages become artifacts identified by their purl (externalRefs) when it
+is unique in the document, else by artifact:spdx:<documentNamespace>#<SPDXID>,
+as files and snippets always are. Checksums fill Hash and the sha256/sha1...
+metadata keys, so astra map's digest linking finds the same content in
+other sources. The document itself is an "sbom" artifact produced by a
+creation step that its creators (Tool/Organization/Person) perform.
+
+Dependency-like relationships (DEPENDS_ON, CONTAINS, GENERATED_FROM,
+*_DEPENDENCY_OF, BUILD_TOOL_OF ...) become a dependency step of the
+dependent element that consumes the other, COPY_OF and VARIANT_OF become
+same_as. Any other type (DESCRIBES, OTHER ...) says nothing about content
+flowing from one element into the other, and astra reads every edge but
+consumes as flow, so it is not an edge: the element lists the related IDs
+under "spdx.<type>" (spdx.describes on the document) and keeps the
+relationship's own fields (comment ...) as JSON under "spdx.<type>.<ID>".
+All element fields are kept as metadata.
+*/
+func SPDX(data []byte) (graph.AstraGraph, []string, error) {
+	root, err := decode(data)
+	if err != nil {
+		return graph.AstraGraph{}, nil, fmt.Errorf("parse spdx json: %w", err)
+	}
+	doc := object(root)
+	if doc == nil || !strings.HasPrefix(str(doc, "spdxVersion"), "SPDX-") {
+		return graph.AstraGraph{}, nil, fmt.Errorf("not an SPDX JSON document (spdxVersion missing)")
+	}
+	ns := cmp.Or(str(doc, "documentNamespace"), str(doc, "name"))
+	sb := &spdxBuilder{builder: newBuilder(), ns: ns, ids: map[string]string{}, ext: map[string]string{}}
+	for _, r := range objects(doc["externalDocumentRefs"]) {
+		sb.ext[str(r, "externalDocumentId")] = str(r, "spdxDocument")
+	}
+
+	docID := "artifact:spdx:" + ns
+	sb.ids["SPDXRef-DOCUMENT"] = docID
+	sb.artifact(graph.Artifact{ID: docID, Kind: "sbom", Name: str(doc, "name"),
+		Metadata: flat(doc, "packages", "files", "snippets", "relationships", "documentDescribes", "name")})
+	sb.creation(doc, docID)
+
+	// a purl names a package only when no other package in the document has it
+	purls := map[string]int{}
+	for _, p := range objects(doc["packages"]) {
+		if u := packagePURL(p); u != "" {
+			purls[u]++
+		}
+	}
+	for _, p := range objects(doc["packages"]) {
+		id := sb.local(str(p, "SPDXID"))
+		u := packagePURL(p)
+		if u != "" && purls[u] == 1 {
+			id = u
+		}
+		sb.ids[str(p, "SPDXID")] = id
+		a := graph.Artifact{ID: id, Kind: "package", Name: str(p, "name"), Version: str(p, "versionInfo"), PURL: u,
+			Metadata: flat(p, "name", "versionInfo")}
+		if ident, ok := mapper.ParsePURL(u); ok {
+			a.Namespace = ident.Namespace
+		}
+		a.Hash = checksums(p, a.Metadata)
+		sb.artifact(a)
+	}
+	for _, f := range objects(doc["files"]) {
+		id := sb.local(str(f, "SPDXID"))
+		sb.ids[str(f, "SPDXID")] = id
+		a := graph.Artifact{ID: id, Kind: "file", Name: str(f, "fileName"), Metadata: flat(f, "fileName")}
+		a.Hash = checksums(f, a.Metadata)
+		sb.artifact(a)
+	}
+	for _, s := range objects(doc["snippets"]) {
+		id := sb.local(str(s, "SPDXID"))
+		sb.ids[str(s, "SPDXID")] = id
+		sb.artifact(graph.Artifact{ID: id, Kind: "snippet", Name: str(s, "name"), Metadata: flat(s, "name")})
+	}
+
+	describes, _ := doc["documentDescribes"].([]any)
+	for _, d := range describes {
+		if id := scalar(d); id != "" {
+			sb.relate("SPDXRef-DOCUMENT", "DESCRIBES", id, nil)
+		}
+	}
+	for _, r := range objects(doc["relationships"]) {
+		sb.relate(str(r, "spdxElementId"), str(r, "relationshipType"), str(r, "relatedSpdxElement"),
+			flat(r, "spdxElementId", "relationshipType", "relatedSpdxElement"))
+	}
+	g, ws := sb.result()
+	return g, ws, nil
+}
+
+type spdxBuilder struct {
+	*builder
+	ns  string
+	ids map[string]string // SPDXID -> node ID
+	ext map[string]string // DocumentRef-x -> namespace
+}
+
+func (sb *spdxBuilder) local(spdxID string) string {
+	return "artifact:spdx:" + sb.ns + "#" + spdxID
+}
+
+// ref resolves an SPDX element reference. Elements of external documents
+// (DocumentRef-x:SPDXRef-y) and undeclared ones get placeholder artifacts.
+func (sb *spdxBuilder) ref(spdxID string) string {
+	if id, ok := sb.ids[spdxID]; ok {
+		return id
+	}
+	var id string
+	if docRef, elem, ok := strings.Cut(spdxID, ":"); ok && strings.HasPrefix(docRef, "DocumentRef-") {
+		id = "artifact:spdx:" + cmp.Or(sb.ext[docRef], docRef) + "#" + elem
+		sb.artifact(graph.Artifact{ID: id, Kind: "spdx-ref", Name: elem, Metadata: map[string]string{"SPDXID": spdxID}})
+	} else {
+		sb.warn("relationship to undeclared element %s", spdxID)
+		id = sb.local(spdxID)
+		sb.artifact(graph.Artifact{ID: id, Kind: "spdx-ref", Name: spdxID, Metadata: map[string]string{"SPDXID": spdxID}})
+	}
+	sb.ids[spdxID] = id
+	return id
+}
+
+func (sb *spdxBuilder) relate(a, typ, b string, md map[string]string) {
+	if a == "" || b == "" || b == "NONE" || b == "NOASSERTION" {
+		return
+	}
+	typ = strings.ToUpper(typ)
+	if md == nil {
+		md = map[string]string{}
+	}
+	md["relationship"] = typ
+	from, to := sb.ref(a), sb.ref(b)
+	switch {
+	case spdxInward[typ]:
+		sb.edge(sb.depStep(from, "spdx"), to, "consumes", md)
+	case spdxOutward[typ]:
+		sb.edge(sb.depStep(to, "spdx"), from, "consumes", md)
+	case spdxSame[typ]:
+		sb.edge(from, to, "same_as", md)
+	default:
+		fm := sb.meta(from)
+		if fm == nil {
+			return
+		}
+		key := "spdx." + strings.ToLower(typ)
+		appendList(fm, key, to)
+		delete(md, "relationship")
+		if len(md) > 0 {
+			if b, err := json.Marshal(md); err == nil {
+				fm[key+"."+to] = string(b)
+			}
+		}
+	}
+}
+
+// creation adds the step that produced the document, performed by its
+// creators. A Person with an e-mail address gets the principal:<email> ID
+// the git parser uses, so commits and SBOMs by one person meet.
+func (sb *spdxBuilder) creation(doc map[string]any, docID string) {
+	ci := object(doc["creationInfo"])
+	sid := "step:spdx:" + sb.ns
+	sb.step(graph.Step{ID: sid, Command: "spdx document creation", Timestamp: str(ci, "created"),
+		Metadata: map[string]string{"source": "spdx"}})
+	sb.edge(sid, docID, "produces", nil)
+	creators, _ := ci["creators"].([]any)
+	for _, c := range creators {
+		raw := scalar(c)
+		kind, who, ok := strings.Cut(raw, ":")
+		if !ok {
+			continue
+		}
+		who = strings.TrimSpace(who)
+		name, email := who, ""
+		if i := strings.LastIndex(who, "("); i >= 0 && strings.HasSuffix(who, ")") {
+			name, email = strings.TrimSpace(who[:i]), strings.TrimSpace(who[i+1:len(who)-1])
+		}
+		var id string
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "person":
+			id = "principal:person:" + name
+			if email != "" {
+				id = "principal:" + email
+			}
+		case "organization":
+			id = "principal:org:" + name
+		case "tool":
+			id = "principal:tool:" + who
+		default:
+			id = "principal:" + strings.ToLower(strings.TrimSpace(kind)) + ":" + who
+		}
+		sb.principal(graph.Principal{ID: id, Name: name, Trust: "unknown", Metadata: map[string]string{"spdx_creator": raw}})
+		sb.edge(id, sid, "performs", nil)
+	}
+}
+
+// packagePURL returns the purl from a package's externalRefs.
+func packagePURL(p map[string]any) string {
+	for _, r := range objects(p["externalRefs"]) {
+		if strings.EqualFold(str(r, "referenceType"), "purl") {
+			return str(r, "referenceLocator")
+		}
+	}
+	return ""
+}
+
+// checksums copies each checksum to md as "<alg>" (sha256, sha1...) and
+// returns the strongest as "<alg>:<value>" for Artifact.Hash.
+func checksums(obj map[string]any, md map[string]string) string {
+	best, rank := "", -1
+	order := []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512", "sha3-256", "sha3-384", "sha3-512"}
+	for _, c := range objects(obj["checksums"]) {
+		alg := strings.ToLower(strings.ReplaceAll(str(c, "algorithm"), "_", "-"))
+		v := strings.ToLower(str(c, "checksumValue"))
+		if alg == "" || v == "" {
+			continue
+		}
+		if _, ok := md[alg]; !ok {
+			md[alg] = v
+		}
+		for i, o := range order {
+			if o == alg && i > rank {
+				best, rank = alg+":"+v, i
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,250 @@
+package mapper
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Identity is the structured form of an artifact or resource ID.
+// It is what lets us correlate the same package across sources
+// (a Debian build dependency, an npm module, a Go module, a git file).
+type Identity struct {
+	Type       string            `json:"type"`                 // purl type: github, gitlab, deb, npm, golang, generic ...
+	Namespace  string            `json:"namespace,omitempty"`  // e.g. owner, distro, npm scope
+	Name       string            `json:"name"`                 // package or repository name
+	Version    string            `json:"version,omitempty"`    // commit hash, package version
+	Qualifiers map[string]string `json:"qualifiers,omitempty"` // e.g. arch=amd64
+	Subpath    string            `json:"subpath,omitempty"`    // file path inside a repository
+}
+
+// ID prefixes of the git parser's file and commit artifacts.
+const (
+	GitFilePrefix   = "artifact:gitfile:"
+	GitCommitPrefix = "artifact:gitcommit:"
+	purlPrefix      = "pkg:"
+)
+
+/*
+ParseArtifactID parses the ID schemes emitted by our parsers:
+
+	artifact:gitfile:<host>/<owner>/<repo>@<commit>:<path>
+	artifact:gitcommit:<host>/<owner>/<repo>@<commit>
+	<pkg>@<version>                     (buildinfo build dependencies)
+	<name>_<version>_<arch>.deb         (buildinfo outputs)
+	pkg:<type>/<namespace>/<name>@<version>?<qualifiers>#<subpath>
+
+The slug never contains "@", so the version is taken from the first "@"
+after the prefix; everything after the next ":" is the file path, which
+may itself contain "@" or ":" (e.g. node_modules/@scope/pkg).
+ok is false when the ID does not match any known scheme.
+*/
+func ParseArtifactID(id string) (Identity, bool) {
+	id = strings.TrimSpace(id)
+	switch {
+	case strings.HasPrefix(id, GitFilePrefix):
+		rest := strings.TrimPrefix(id, GitFilePrefix)
+		slug, ref, ok := strings.Cut(rest, "@")
+		if !ok {
+			return Identity{}, false
+		}
+		hash, path, ok := strings.Cut(ref, ":")
+		if !ok {
+			return Identity{}, false
+		}
+		ident := identityFromSlug(slug)
+		ident.Version = hash
+		ident.Subpath = path
+		return ident, true
+
+	case strings.HasPrefix(id, GitCommitPrefix):
+		rest := strings.TrimPrefix(id, GitCommitPrefix)
+		slug, hash, ok := strings.Cut(rest, "@")
+		if !ok {
+			return Identity{}, false
+		}
+		ident := identityFromSlug(slug)
+		ident.Version = hash
+		return ident, true
+
+	case strings.HasPrefix(id, purlPrefix):
+		return ParsePURL(id)
+
+	case strings.HasSuffix(id, ".deb"):
+		// <name>_<version>_<arch>.deb (epoch is dropped from file names)
+		parts := strings.Split(strings.TrimSuffix(id, ".deb"), "_")
+		if len(parts) != 3 {
+			return Identity{}, false
+		}
+		return Identity{
+			Type:       "deb",
+			Namespace:  "debian",
+			Name:       parts[0],
+			Version:    parts[1],
+			Qualifiers: map[string]string{"arch": parts[2]},
+		}, true
+
+	case strings.Count(id, "@") == 1 && !strings.ContainsAny(id, "/ "):
+		// buildinfo build dependency: <pkg>@<version>
+		name, ver, _ := strings.Cut(id, "@")
+		if name == "" || ver == "" {
+			return Identity{}, false
+		}
+		return Identity{Type: "deb", Namespace: "debian", Name: name, Version: ver}, true
+	}
+	return Identity{}, false
+}
+
+// identityFromSlug maps "host/owner/repo" to a purl type and namespace.
+// Well known forges get their own purl type, anything else is generic
+// with the host kept in the namespace.
+func identityFromSlug(slug string) Identity {
+	parts := strings.Split(strings.Trim(slug, "/"), "/")
+	if len(parts) < 2 {
+		return Identity{Type: "generic", Name: slug}
+	}
+	host := parts[0]
+	name := parts[len(parts)-1]
+	owner := strings.Join(parts[1:len(parts)-1], "/")
+
+	switch host {
+	case "github.com":
+		return Identity{Type: "github", Namespace: owner, Name: name}
+	case "gitlab.com":
+		return Identity{Type: "gitlab", Namespace: owner, Name: name}
+	case "bitbucket.org":
+		return Identity{Type: "bitbucket", Namespace: owner, Name: name}
+	}
+	ns := host
+	if owner != "" {
+		ns = host + "/" + owner
+	}
+	return Identity{Type: "generic", Namespace: ns, Name: name}
+}
+
+// PURL renders the identity as a Package URL
+// (https://github.com/package-url/purl-spec). It returns "" when the
+// identity has no type or name.
+func (id Identity) PURL() string {
+	if id.Type == "" || id.Name == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(purlPrefix)
+	b.WriteString(strings.ToLower(id.Type))
+	b.WriteString("/")
+	if id.Namespace != "" {
+		for _, seg := range strings.Split(id.Namespace, "/") {
+			if seg == "" {
+				continue
+			}
+			b.WriteString(purlEscape(seg))
+			b.WriteString("/")
+		}
+	}
+	b.WriteString(purlEscape(id.Name))
+	if id.Version != "" {
+		b.WriteString("@")
+		b.WriteString(purlEscape(id.Version))
+	}
+	if len(id.Qualifiers) > 0 {
+		keys := make([]string, 0, len(id.Qualifiers))
+		for k := range id.Qualifiers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i == 0 {
+				b.WriteString("?")
+			} else {
+				b.WriteString("&")
+			}
+			b.WriteString(strings.ToLower(k))
+			b.WriteString("=")
+			b.WriteString(purlEscape(id.Qualifiers[k]))
+		}
+	}
+	if id.Subpath != "" {
+		var segs []string
+		for _, seg := range strings.Split(id.Subpath, "/") {
+			if seg == "" || seg == "." || seg == ".." {
+				continue
+			}
+			segs = append(segs, purlEscape(seg))
+		}
+		if len(segs) > 0 {
+			b.WriteString("#")
+			b.WriteString(strings.Join(segs, "/"))
+		}
+	}
+	return b.String()
+}
+
+// ParsePURL parses a Package URL into an Identity.
+func ParsePURL(s string) (Identity, bool) {
+	if !strings.HasPrefix(s, purlPrefix) {
+		return Identity{}, false
+	}
+	rest := strings.TrimPrefix(s, purlPrefix)
+
+	var ident Identity
+	if i := strings.Index(rest, "#"); i >= 0 {
+		ident.Subpath = purlUnescapePath(rest[i+1:])
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "?"); i >= 0 {
+		for _, kv := range strings.Split(rest[i+1:], "&") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" || v == "" {
+				continue
+			}
+			if ident.Qualifiers == nil {
+				ident.Qualifiers = map[string]string{}
+			}
+			// not url.ParseQuery: "+" is literal in purl (Debian versions)
+			ident.Qualifiers[strings.ToLower(k)] = purlUnescape(v)
+		}
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		ident.Version = purlUnescape(rest[i+1:])
+		rest = rest[:i]
+	}
+
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" {
+		return Identity{}, false
+	}
+	ident.Type = strings.ToLower(parts[0])
+	ident.Name = purlUnescape(parts[len(parts)-1])
+	var ns []string
+	for _, seg := range parts[1 : len(parts)-1] {
+		ns = append(ns, purlUnescape(seg))
+	}
+	ident.Namespace = strings.Join(ns, "/")
+	if ident.Name == "" {
+		return Identity{}, false
+	}
+	return ident, true
+}
+
+// purlEscape percent-encodes a single purl segment. "@" must be encoded
+// since it separates the version (e.g. npm scopes: %40scope).
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
+
+func purlUnescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+func purlUnescapePath(s string) string {
+	segs := strings.Split(s, "/")
+	for i, seg := range segs {
+		segs[i] = purlUnescape(seg)
+	}
+	return strings.Join(segs, "/")
+}
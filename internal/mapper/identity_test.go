@@ -0,0 +1,79 @@
+package mapper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArtifactID(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	for _, tc := range []struct {
+		id   string
+		want Identity
+		ok   bool
+	}{
+		{
+			"artifact:gitfile:github.com/acme/app@" + sha + ":cmd/main.go",
+			Identity{Type: "github", Namespace: "acme", Name: "app", Version: sha, Subpath: "cmd/main.go"}, true,
+		},
+		{
+			"artifact:gitfile:github.com/acme/app@" + sha + ":dir/a@b.txt",
+			Identity{Type: "github", Namespace: "acme", Name: "app", Version: sha, Subpath: "dir/a@b.txt"}, true,
+		},
+		{
+			"artifact:gitfile:github.com/acme/app@" + sha + ":node_modules/@scope/pkg/index.js",
+			Identity{Type: "github", Namespace: "acme", Name: "app", Version: sha, Subpath: "node_modules/@scope/pkg/index.js"}, true,
+		},
+		{
+			"artifact:gitfile:github.com/acme/app@" + sha + ":c:d.txt",
+			Identity{Type: "github", Namespace: "acme", Name: "app", Version: sha, Subpath: "c:d.txt"}, true,
+		},
+		{
+			"artifact:gitcommit:git.example.org/team/sub/app@" + sha,
+			Identity{Type: "generic", Namespace: "git.example.org/team/sub", Name: "app", Version: sha}, true,
+		},
+		{
+			"gcc-12@12.2.0-14",
+			Identity{Type: "deb", Namespace: "debian", Name: "gcc-12", Version: "12.2.0-14"}, true,
+		},
+		{
+			"hello_2.10-3_amd64.deb",
+			Identity{Type: "deb", Namespace: "debian", Name: "hello", Version: "2.10-3", Qualifiers: map[string]string{"arch": "amd64"}}, true,
+		},
+		{"pkg:npm/%40scope/pkg@1.0.0", Identity{Type: "npm", Namespace: "@scope", Name: "pkg", Version: "1.0.0"}, true},
+		{"artifact:gitfile:github.com/acme/app", Identity{}, false},
+		{"artifact:gitfile:github.com/acme/app@" + sha, Identity{}, false},
+		{"a/b@1", Identity{}, false},
+		{"just-a-name", Identity{}, false},
+	} {
+		got, ok := ParseArtifactID(tc.id)
+		if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseArtifactID(%q) = %+v, %v, want %+v, %v", tc.id, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestPURLRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		id   Identity
+		purl string
+	}{
+		{Identity{Type: "npm", Namespace: "@scope", Name: "pkg", Version: "1.0.0"}, "pkg:npm/%40scope/pkg@1.0.0"},
+		{Identity{Type: "deb", Namespace: "debian", Name: "libc6", Version: "2.36-9+deb12u4", Qualifiers: map[string]string{"arch": "amd64"}}, "pkg:deb/debian/libc6@2.36-9+deb12u4?arch=amd64"},
+		{Identity{Type: "github", Namespace: "acme", Name: "app", Version: "abc", Subpath: "dir/a@b.txt"}, "pkg:github/acme/app@abc#dir/a%40b.txt"},
+		{Identity{Type: "golang", Namespace: "golang.org/x", Name: "net", Version: "v0.1.0"}, "pkg:golang/golang.org/x/net@v0.1.0"},
+	} {
+		if got := tc.id.PURL(); got != tc.purl {
+			t.Errorf("PURL(%+v) = %q, want %q", tc.id, got, tc.purl)
+		}
+		back, ok := ParsePURL(tc.purl)
+		if !ok || !reflect.DeepEqual(back, tc.id) {
+			t.Errorf("ParsePURL(%q) = %+v, %v, want %+v", tc.purl, back, ok, tc.id)
+		}
+	}
+	for _, s := range []string{"npm/pkg@1", "pkg:npm", "pkg:/pkg@1", "pkg:npm/@1"} {
+		if id, ok := ParsePURL(s); ok {
+			t.Errorf("ParsePURL(%q) = %+v, want not ok", s, id)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package mapper
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// LinkMode selects what LinkByDigest does with artifacts sharing a digest.
+type LinkMode string
+
+const (
+	LinkNone  LinkMode = "none"  // leave the graph untouched
+	LinkEdges LinkMode = "edges" // add same_as edges between matching artifacts
+	LinkMerge LinkMode = "merge" // collapse matching artifacts into one node
+)
+
+// digestAttrs are the metadata keys parsers use for content digests.
+// "digest.<alg>" / "digest:<alg>" (SLSA / in-toto digest sets) are handled too.
+var digestAttrs = []string{"hash", "content-hash", "sha256", "sha1", "sha512", "sha384"}
+
+/*
+LinkByDigest runs after ToAstraGraph and connects artifacts that are the
+same content seen by different sources: a git blob, a buildinfo
+Checksums-Sha256 entry, a SLSA subject digest.
+Every digest an artifact carries is indexed as "<alg>:<hex>"; artifacts
+sharing any key form one group (transitively).
+In LinkEdges mode the smallest ID of a group gets a same_as edge to every
+other member. In LinkMerge mode the group collapses into that ID: edges are
+rewritten, metadata is merged (first wins) and the other IDs are kept in
+metadata["aliases"].
+
+Git blob IDs ("gitoid") are a weaker signal: every empty file and every
+copied LICENSE share one. They only link the same path of one repository,
+or git objects of different repositories, and always by same_as edges, in
+LinkMerge mode too: merging a file reverted to earlier content into the
+earlier version's node would make its steps a produce/consume cycle.
+*/
+func LinkByDigest(g graph.AstraGraph, mode LinkMode) graph.AstraGraph {
+	if mode == "" || mode == LinkNone {
+		return g
+	}
+
+	// union-find over artifact indices
+	parent := make([]int, len(g.Artifacts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	byDigest := map[string]int{}
+	byGitoid := map[string][]int{}
+	shared := map[int]string{} // artifact index -> a digest it was linked by
+	for i, a := range g.Artifacts {
+		for _, d := range ArtifactDigests(a) {
+			if strings.HasPrefix(d, "gitoid:") {
+				byGitoid[d] = append(byGitoid[d], i)
+				continue
+			}
+			j, ok := byDigest[d]
+			if !ok {
+				byDigest[d] = i
+				continue
+			}
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[ri] = rj
+			}
+			shared[i] = d
+			shared[j] = d
+		}
+	}
+
+	groups := map[int][]int{}
+	for i := range g.Artifacts {
+		r := find(i)
+		groups[r] = append(groups[r], i)
+	}
+
+	canon := map[string]string{} // artifact ID -> canonical ID
+	for _, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(a, b int) bool { return g.Artifacts[members[a]].ID < g.Artifacts[members[b]].ID })
+		c := g.Artifacts[members[0]].ID
+		for _, m := range members[1:] {
+			if id := g.Artifacts[m].ID; id != c {
+				canon[id] = c
+			}
+		}
+	}
+	gitLinks := gitoidLinks(g.Artifacts, byGitoid)
+	if len(canon) == 0 && len(gitLinks) == 0 {
+		return g
+	}
+	// don't reorder or grow the caller's slices
+	g.Artifacts = append([]graph.Artifact(nil), g.Artifacts...)
+	g.Edges = append([]graph.Edge(nil), g.Edges...)
+
+	switch mode {
+	case LinkEdges:
+		for i, a := range g.Artifacts {
+			c, ok := canon[a.ID]
+			if !ok {
+				continue
+			}
+			g.Edges = append(g.Edges, graph.Edge{
+				Source:   c,
+				Target:   a.ID,
+				Relation: "same_as",
+				Metadata: map[string]string{"digest": shared[i]},
+			})
+		}
+		g.Edges = append(g.Edges, gitLinks...)
+
+	case LinkMerge:
+		merged := map[string]*graph.Artifact{}
+		var arts []graph.Artifact
+		for _, a := range g.Artifacts {
+			if _, ok := canon[a.ID]; !ok {
+				cp := a
+				cp.Metadata = cloneMap(a.Metadata)
+				arts = append(arts, cp)
+			}
+		}
+		for i := range arts {
+			merged[arts[i].ID] = &arts[i]
+		}
+		aliases := map[string][]string{}
+		for _, a := range g.Artifacts {
+			c, ok := canon[a.ID]
+			if !ok {
+				continue
+			}
+			dst := merged[c]
+			mergeArtifact(dst, a)
+			aliases[c] = append(aliases[c], a.ID)
+		}
+		for c, ids := range aliases {
+			sort.Strings(ids)
+			if merged[c].Metadata == nil {
+				merged[c].Metadata = map[string]string{}
+			}
+			merged[c].Metadata["aliases"] = strings.Join(ids, ",")
+		}
+		g.Artifacts = arts
+		g.Edges = append(g.Edges, gitLinks...)
+
+		rename := func(id string) string {
+			if c, ok := canon[id]; ok {
+				return c
+			}
+			return id
+		}
+		seen := map[string]bool{}
+		var edges []graph.Edge
+		for _, e := range g.Edges {
+			e.Source, e.Target = rename(e.Source), rename(e.Target)
+			if e.Relation == "same_as" && e.Source == e.Target {
+				continue
+			}
+			k := e.Source + "|" + e.Relation + "|" + e.Target
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			edges = append(edges, e)
+		}
+		g.Edges = edges
+	}
+
+	SortGraph(&g)
+	return g
+}
+
+/*
+gitoidLinks returns same_as edges between git objects with the same blob ID
+that are the same path of one repository, or in different repositories.
+Objects are classed by repository and path; the smallest ID of each class
+links to the rest of it, and the smallest ID overall links to the smallest
+of every class in another repository.
+*/
+func gitoidLinks(arts []graph.Artifact, byGitoid map[string][]int) []graph.Edge {
+	var out []graph.Edge
+	link := func(src, dst, digest string) {
+		out = append(out, graph.Edge{Source: src, Target: dst, Relation: "same_as", Metadata: map[string]string{"digest": digest}})
+	}
+	for d, members := range byGitoid {
+		if len(members) < 2 {
+			continue
+		}
+		type class struct{ repo, path string }
+		classes := map[class][]string{}
+		for _, i := range members {
+			a := arts[i]
+			c := class{repo: "id:" + a.ID}
+			if ident, ok := ParseArtifactID(a.ID); ok {
+				c = class{repo: ident.Type + "/" + ident.Namespace + "/" + ident.Name, path: ident.Subpath}
+			}
+			classes[c] = append(classes[c], a.ID)
+		}
+		keys := make([]class, 0, len(classes))
+		for c, ids := range classes {
+			sort.Strings(ids)
+			keys = append(keys, c)
+		}
+		sort.Slice(keys, func(i, j int) bool { return classes[keys[i]][0] < classes[keys[j]][0] })
+		for _, c := range keys {
+			ids := classes[c]
+			for _, id := range ids[1:] {
+				if id != ids[0] {
+					link(ids[0], id, d)
+				}
+			}
+		}
+		first := keys[0]
+		for _, c := range keys[1:] {
+			if c.repo != first.repo {
+				link(classes[first][0], classes[c][0], d)
+			}
+		}
+	}
+	return out
+}
+
+// mergeArtifact fills empty fields of dst from src; dst wins on conflicts.
+func mergeArtifact(dst *graph.Artifact, src graph.Artifact) {
+	if dst.Name == "" {
+		dst.Name = src.Name
+	}
+	if dst.Namespace == "" {
+		dst.Namespace = src.Namespace
+	}
+	if dst.Version == "" {
+		dst.Version = src.Version
+	}
+	if dst.PURL == "" {
+		dst.PURL = src.PURL
+	}
+	if dst.Hash == "" {
+		dst.Hash = src.Hash
+	}
+	if dst.Size == 0 {
+		dst.Size = src.Size
+	}
+	if dst.Metadata == nil && len(src.Metadata) > 0 {
+		dst.Metadata = map[string]string{}
+	}
+	for k, v := range src.Metadata {
+		if _, ok := dst.Metadata[k]; !ok {
+			dst.Metadata[k] = v
+		}
+	}
+}
+
+// ArtifactDigests returns every digest an artifact carries as "<alg>:<hex>".
+// Git object IDs are not content hashes, so git artifacts use the "gitoid"
+// algorithm and only ever match other git objects.
+func ArtifactDigests(a graph.Artifact) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(alg, v string) {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if pre, h, ok := strings.Cut(v, ":"); ok {
+			alg, v = pre, h // already prefixed, e.g. "sha256:..."
+		}
+		if v == "" || !isHex(v) {
+			return
+		}
+		if alg == "" {
+			alg = digestAlg(a, v)
+		}
+		if alg == "" {
+			return
+		}
+		d := alg + ":" + v
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+
+	add("", a.Hash)
+	for _, k := range digestAttrs {
+		if v, ok := a.Metadata[k]; ok {
+			alg := ""
+			if strings.HasPrefix(k, "sha") {
+				alg = k
+			}
+			add(alg, v)
+		}
+	}
+	for k, v := range a.Metadata {
+		if alg, ok := strings.CutPrefix(k, "digest."); ok {
+			add(strings.ToLower(alg), v)
+		} else if alg, ok := strings.CutPrefix(k, "digest:"); ok {
+			add(strings.ToLower(alg), v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// digestAlg infers the algorithm of an unlabelled hex digest.
+func digestAlg(a graph.Artifact, hex string) string {
+	if strings.HasPrefix(a.Kind, "git-") {
+		return "gitoid"
+	}
+	switch len(hex) {
+	case 40:
+		return "sha1"
+	case 64:
+		return "sha256"
+	case 96:
+		return "sha384"
+	case 128:
+		return "sha512"
+	}
+	return ""
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package mapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+const (
+	sha256A = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	blobA   = "1111111111111111111111111111111111111111"
+)
+
+func gitFile(repo, commit, path string) graph.Artifact {
+	return graph.Artifact{ID: GitFilePrefix + repo + "@" + commit + ":" + path, Kind: "git-file", Hash: blobA}
+}
+
+func edgeSet(g graph.AstraGraph, rel string) map[string]bool {
+	out := map[string]bool{}
+	for _, e := range g.Edges {
+		if e.Relation == rel {
+			out[e.Source+" -> "+e.Target] = true
+		}
+	}
+	return out
+}
+
+func TestArtifactDigests(t *testing.T) {
+	for _, tc := range []struct {
+		a    graph.Artifact
+		want string
+	}{
+		{graph.Artifact{Hash: sha256A}, "sha256:" + sha256A},
+		{graph.Artifact{Hash: "SHA256:" + strings.ToUpper(sha256A)}, "sha256:" + sha256A},
+		{graph.Artifact{Metadata: map[string]string{"digest.sha256": sha256A}}, "sha256:" + sha256A},
+		{graph.Artifact{Metadata: map[string]string{"sha1": blobA}}, "sha1:" + blobA},
+		{graph.Artifact{Kind: "git-file", Hash: blobA}, "gitoid:" + blobA},
+		{graph.Artifact{Hash: "not-hex"}, ""},
+		{graph.Artifact{Hash: "abcd"}, ""},
+	} {
+		if got := strings.Join(ArtifactDigests(tc.a), ","); got != tc.want {
+			t.Errorf("ArtifactDigests(%+v) = %q, want %q", tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestLinkByDigest(t *testing.T) {
+	in := graph.AstraGraph{
+		Artifacts: []graph.Artifact{
+			{ID: "hello_2.10-3_amd64.deb", Name: "hello", Hash: sha256A},
+			{ID: "pkg:deb/debian/hello@2.10-3", Metadata: map[string]string{"digest.sha256": sha256A, "source": "slsa"}},
+			{ID: "unrelated", Hash: strings.Repeat("b", 64)},
+		},
+		Steps: []graph.Step{{ID: "build"}, {ID: "deploy"}},
+		Edges: []graph.Edge{
+			{Source: "build", Target: "hello_2.10-3_amd64.deb", Relation: "produces"},
+			{Source: "pkg:deb/debian/hello@2.10-3", Target: "deploy", Relation: "consumes"},
+		},
+	}
+
+	if got := LinkByDigest(in, LinkNone); len(got.Edges) != 2 || len(got.Artifacts) != 3 {
+		t.Errorf("LinkNone changed the graph: %+v", got)
+	}
+
+	g := LinkByDigest(in, LinkEdges)
+	same := edgeSet(g, "same_as")
+	if len(same) != 1 || !same["hello_2.10-3_amd64.deb -> pkg:deb/debian/hello@2.10-3"] {
+		t.Errorf("same_as edges = %v", same)
+	}
+	if len(in.Edges) != 2 {
+		t.Errorf("LinkEdges grew the caller's edges: %v", in.Edges)
+	}
+
+	g = LinkByDigest(in, LinkMerge)
+	if len(g.Artifacts) != 2 {
+		t.Fatalf("merged artifacts = %+v, want 2", g.Artifacts)
+	}
+	var merged graph.Artifact
+	for _, a := range g.Artifacts {
+		if a.ID == "hello_2.10-3_amd64.deb" {
+			merged = a
+		}
+	}
+	if merged.Metadata["aliases"] != "pkg:deb/debian/hello@2.10-3" || merged.Metadata["source"] != "slsa" || merged.Name != "hello" {
+		t.Errorf("merged artifact = %+v", merged)
+	}
+	if e := edgeSet(g, "consumes"); !e["hello_2.10-3_amd64.deb -> deploy"] {
+		t.Errorf("consumes edge not rewritten: %v", g.Edges)
+	}
+	if len(in.Artifacts) != 3 || in.Artifacts[1].Metadata["aliases"] != "" {
+		t.Errorf("LinkMerge changed the caller's artifacts: %+v", in.Artifacts)
+	}
+}
+
+func TestLinkByDigestGitoid(t *testing.T) {
+	const (
+		c1 = "0000000000000000000000000000000000000001"
+		c2 = "0000000000000000000000000000000000000002"
+	)
+	reverted1 := gitFile("github.com/acme/app", c1, "main.go")
+	reverted2 := gitFile("github.com/acme/app", c2, "main.go")
+	otherPath := gitFile("github.com/acme/app", c1, "LICENSE")
+	otherRepo := gitFile("github.com/acme/lib", c1, "vendor/main.go")
+	in := graph.AstraGraph{Artifacts: []graph.Artifact{reverted1, reverted2, otherPath, otherRepo}}
+
+	for _, mode := range []LinkMode{LinkEdges, LinkMerge} {
+		g := LinkByDigest(in, mode)
+		if len(g.Artifacts) != 4 {
+			t.Errorf("%s: git objects were merged: %d artifacts", mode, len(g.Artifacts))
+		}
+		same := edgeSet(g, "same_as")
+		for _, want := range []string{
+			reverted1.ID + " -> " + reverted2.ID,
+			otherPath.ID + " -> " + otherRepo.ID,
+		} {
+			if !same[want] {
+				t.Errorf("%s: missing same_as %s in %v", mode, want, same)
+			}
+		}
+		if len(same) != 2 {
+			t.Errorf("%s: same_as edges = %v, want 2", mode, same)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package mapper
 
 import (
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/abuishgair/astra/internal/graph"
 	"github.com/abuishgair/astra/internal/parser"
@@ -11,15 +14,25 @@ import (
 // Relations emitted:
 //
 //	principal --uses--> resource
+//	principal --performs--> step
 //	resource  --carries_out--> step
 //	step  --consumes--> artifact
 //	step      --produces--> artifact
 func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
+	return ToAstraGraphWithRules(m, nil)
+}
+
+// ToAstraGraphWithRules is ToAstraGraph with the field mapping, trust
+// defaults and extra relations for m.Source taken from rules (may be nil).
+func ToAstraGraphWithRules(m parser.Mapped, rules *Rules) graph.AstraGraph {
+	sr := rules.For(m.Source)
+
 	arts := map[string]graph.Artifact{}
 	steps := map[string]graph.Step{}
 	princs := map[string]graph.Principal{}
 	resources := map[string]graph.Resource{}
 	edges := map[string]graph.Edge{}
+	keyAt := map[string]time.Time{} // principal -> time of its latest signed output
 
 	addEdge := func(src, dst, rel string, md map[string]string) {
 		if src == "" || dst == "" || rel == "" {
@@ -29,9 +42,7 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 		if _, ok := edges[k]; ok {
 			return
 		}
-		edges[k] = graph.Edge{Source: src, Target: dst, Relation: rel}
-		// If we add Edge.Metadata later, we can assign md here.
-		_ = md
+		edges[k] = graph.Edge{Source: src, Target: dst, Relation: rel, Metadata: md}
 	}
 
 	for _, rec := range m.Mapped {
@@ -43,14 +54,20 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 					md = map[string]string{}
 				}
 
+				trust, builder := normalizePrincipal(md, sr)
+				name := rec.Principal.Label
+				if v := pick(md, sr.principalKeys("name")); v != "" {
+					name = v
+				}
 				princs[rec.Principal.ID] = graph.Principal{
 					ID:       rec.Principal.ID,
-					Trust:    "unknown",
-					Builder:  "",
-					Name:     rec.Principal.Label,
+					Trust:    trust,
+					Builder:  builder,
+					Name:     name,
 					Metadata: md,
 				}
 			}
+			trackSeen(princs[rec.Principal.ID].Metadata, recordTime(rec, sr))
 		}
 
 		// --- Step ---
@@ -61,13 +78,14 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 					md = map[string]string{}
 				}
 				//TODO add environment
-				steps[rec.Step.ID] = graph.Step{
-					ID:        rec.Step.ID,
-					Command:   normalizeStepCommand(md),
-					Timestamp: normalizeTimestamp(md), // expects env["time"] if present
-					Arch:      normalizeStepArch(md),
-					Metadata:  md,
+				st := graph.Step{
+					ID:       rec.Step.ID,
+					Command:  normalizeStepCommand(md, sr),
+					Arch:     normalizeStepArch(md, sr),
+					Metadata: md,
 				}
+				st.SetTimestamp(normalizeTimestamp(md, sr)) // expects env["time"] if present
+				steps[rec.Step.ID] = st
 			}
 		}
 
@@ -80,9 +98,10 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 			if _, ok := resources[r.ID]; !ok {
 				resources[r.ID] = graph.Resource{
 					ID:     r.ID,
-					Type:   normalizeResourceType(r),
-					URI:    normalizeResourceURI(r),
-					Format: normalizeResourceFormat(r),
+					Type:   normalizeResourceType(r, sr),
+					URI:    normalizeResourceURI(r, sr),
+					Format: normalizeResourceFormat(r, sr),
+					PURL:   normalizeResourcePURL(r, sr),
 				}
 			}
 		}
@@ -93,9 +112,11 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 				continue
 			}
 			if _, ok := arts[it.ID]; !ok {
-				arts[it.ID] = normalizeArtifact(it)
+				arts[it.ID] = normalizeArtifact(it, sr)
+			}
+			if sr.defaultRelations() {
+				addEdge(rec.Step.ID, it.ID, "consumes", nil)
 			}
-			addEdge(rec.Step.ID, it.ID, "consumes", nil)
 		}
 
 		// --- Artifacts (Out) ---
@@ -104,16 +125,39 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 				continue
 			}
 			if _, ok := arts[it.ID]; !ok {
-				arts[it.ID] = normalizeArtifact(it)
+				arts[it.ID] = normalizeArtifact(it, sr)
+			}
+			if sr.defaultRelations() {
+				addEdge(rec.Step.ID, it.ID, "produces", nil)
+			}
+			if p, ok := princs[rec.Principal.ID]; ok {
+				if k := graph.SigningKey(it.Attrs); k != "" {
+					trackKey(p.Metadata, k, recordTime(rec, sr), keyAt, p.ID)
+				}
 			}
-			addEdge(rec.Step.ID, it.ID, "produces", nil)
 		}
 
 		// --- Edges: principal/resource/step ---
 
-		for _, r := range rec.Resources {
-			addEdge(rec.Principal.ID, r.ID, "uses", nil)
-			addEdge(r.ID, rec.Step.ID, "carries_out", nil)
+		if sr.defaultRelations() {
+			// Resources such as resource:git are shared by every record, so
+			// this is the only edge that attributes a step to its principal.
+			addEdge(rec.Principal.ID, rec.Step.ID, "performs", nil)
+			for _, r := range rec.Resources {
+				addEdge(rec.Principal.ID, r.ID, "uses", nil)
+				addEdge(r.ID, rec.Step.ID, "carries_out", nil)
+			}
+		}
+
+		// --- Edges: declared by rules ---
+		if sr != nil {
+			for _, rel := range sr.Relations {
+				for _, src := range roleIDs(rec, rel.From) {
+					for _, dst := range roleIDs(rec, rel.To) {
+						addEdge(src, dst, rel.Relation, nil)
+					}
+				}
+			}
 		}
 
 	}
@@ -136,7 +180,66 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 	for _, e := range edges {
 		out.Edges = append(out.Edges, e)
 	}
-	// Ensure deterministic, ordering of nodes and edges.
+	SortGraph(&out)
+
+	return out
+}
+
+// recordTime is when a record happened: the step timestamp, else the
+// "time" attr of what it produced (git commit artifacts).
+func recordTime(rec parser.Record, sr *SourceRules) (t time.Time) {
+	if ts, ok := graph.ParseTimestamp(normalizeTimestamp(rec.Step.Attrs, sr)); ok {
+		return ts
+	}
+	for _, it := range rec.ArtifactsOut {
+		if ts, ok := graph.ParseTimestamp(it.Attrs["time"]); ok {
+			return ts
+		}
+	}
+	return t
+}
+
+// trackSeen widens the principal's first_seen/last_seen (unix seconds) to t.
+// These feed recency-based trust and anomaly detection.
+func trackSeen(md map[string]string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if first, ok := graph.ParseTimestamp(md["first_seen"]); !ok || t.Before(first) {
+		md["first_seen"] = strconv.FormatInt(t.Unix(), 10)
+	}
+	if last, ok := graph.ParseTimestamp(md["last_seen"]); !ok || t.After(last) {
+		md["last_seen"] = strconv.FormatInt(t.Unix(), 10)
+	}
+}
+
+/*
+trackKey records on a principal the key that signed its output (git puts
+it on the commit artifact): every key in "signing_keys" and the latest in
+"signing_key", where trust derivation, scoring and the SLSA assessment
+read it.
+*/
+func trackKey(md map[string]string, key string, t time.Time, latest map[string]time.Time, id string) {
+	keys := map[string]bool{key: true}
+	for _, k := range strings.Split(md["signing_keys"], ",") {
+		if k != "" {
+			keys[k] = true
+		}
+	}
+	list := make([]string, 0, len(keys))
+	for k := range keys {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	md["signing_keys"] = strings.Join(list, ",")
+	if md["signing_key"] == "" || !t.Before(latest[id]) {
+		md["signing_key"] = key
+		latest[id] = t
+	}
+}
+
+// SortGraph ensures deterministic ordering of nodes and edges.
+func SortGraph(out *graph.AstraGraph) {
 	sort.Slice(out.Artifacts, func(i, j int) bool { return out.Artifacts[i].ID < out.Artifacts[j].ID })
 	sort.Slice(out.Steps, func(i, j int) bool { return out.Steps[i].ID < out.Steps[j].ID })
 	sort.Slice(out.Principals, func(i, j int) bool { return out.Principals[i].ID < out.Principals[j].ID })
@@ -150,6 +253,4 @@ func ToAstraGraph(m parser.Mapped) graph.AstraGraph {
 		}
 		return out.Edges[i].Target < out.Edges[j].Target
 	})
-
-	return out
 }
@@ -0,0 +1,239 @@
+package mapper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// ConflictPolicy decides which value wins when the same node ID carries
+// different attributes in different inputs.
+type ConflictPolicy string
+
+const (
+	FirstWins ConflictPolicy = "first-wins"
+	LastWins  ConflictPolicy = "last-wins"
+	// Record keeps the first value and stores the others in
+	// metadata["conflict.<field>"] so nothing is silently dropped.
+	Record ConflictPolicy = "record"
+)
+
+// Conflict is one attribute that differed between inputs for the same node.
+type Conflict struct {
+	Kind  string `json:"kind"` // artifact|step|principal|resource|edge
+	ID    string `json:"id"`
+	Field string `json:"field"`
+	Kept  string `json:"kept"`
+	Other string `json:"other"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %s: %s %q vs %q", c.Kind, c.ID, c.Field, c.Kept, c.Other)
+}
+
+type merger struct {
+	policy    ConflictPolicy
+	conflicts []Conflict
+}
+
+// str merges one string field. Empty values never conflict.
+func (m *merger) str(kind, id, field string, dst *string, src string) {
+	if src == "" || *dst == src {
+		return
+	}
+	if *dst == "" {
+		*dst = src
+		return
+	}
+	c := Conflict{Kind: kind, ID: id, Field: field, Kept: *dst, Other: src}
+	if m.policy == LastWins {
+		c.Kept, c.Other = src, *dst
+		*dst = src
+	}
+	m.conflicts = append(m.conflicts, c)
+}
+
+// strMap merges a metadata-like map key by key.
+func (m *merger) strMap(kind, id, field string, dst *map[string]string, src map[string]string) {
+	if len(src) == 0 {
+		return
+	}
+	if *dst == nil {
+		*dst = map[string]string{}
+	}
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := (*dst)[k]
+		m.str(kind, id, field+"."+k, &v, src[k])
+		(*dst)[k] = v
+	}
+}
+
+/*
+MergeGraphs combines graphs mapped from several inputs (git history, SLSA
+provenance, buildinfo ...) into one, deduplicating nodes and edges by ID.
+Inputs are merged in order; differing non-empty attributes are resolved by
+policy and every difference is returned as a Conflict.
+*/
+func MergeGraphs(gs []graph.AstraGraph, policy ConflictPolicy) (graph.AstraGraph, []Conflict) {
+	m := &merger{policy: policy}
+
+	arts := map[string]*graph.Artifact{}
+	steps := map[string]*graph.Step{}
+	princs := map[string]*graph.Principal{}
+	resources := map[string]*graph.Resource{}
+	edges := map[string]*graph.Edge{}
+
+	for _, g := range gs {
+		for _, a := range g.Artifacts {
+			dst, ok := arts[a.ID]
+			if !ok {
+				cp := a
+				cp.Metadata = cloneMap(a.Metadata)
+				arts[a.ID] = &cp
+				continue
+			}
+			m.str("artifact", a.ID, "kind", &dst.Kind, a.Kind)
+			m.str("artifact", a.ID, "name", &dst.Name, a.Name)
+			m.str("artifact", a.ID, "namespace", &dst.Namespace, a.Namespace)
+			m.str("artifact", a.ID, "version", &dst.Version, a.Version)
+			m.str("artifact", a.ID, "purl", &dst.PURL, a.PURL)
+			m.str("artifact", a.ID, "hash", &dst.Hash, a.Hash)
+			size := sizeString(dst.Size)
+			m.str("artifact", a.ID, "size", &size, sizeString(a.Size))
+			dst.Size, _ = strconv.ParseInt(size, 10, 64)
+			m.strMap("artifact", a.ID, "metadata", &dst.Metadata, a.Metadata)
+		}
+		for _, s := range g.Steps {
+			dst, ok := steps[s.ID]
+			if !ok {
+				cp := s
+				cp.Environment = cloneMap(s.Environment)
+				cp.Metadata = cloneMap(s.Metadata)
+				steps[s.ID] = &cp
+				continue
+			}
+			m.str("step", s.ID, "command", &dst.Command, s.Command)
+			m.str("step", s.ID, "timestamp", &dst.Timestamp, s.Timestamp)
+			m.str("step", s.ID, "architecture", &dst.Arch, s.Arch)
+			m.strMap("step", s.ID, "environment", &dst.Environment, s.Environment)
+			m.strMap("step", s.ID, "metadata", &dst.Metadata, s.Metadata)
+		}
+		for _, p := range g.Principals {
+			dst, ok := princs[p.ID]
+			if !ok {
+				cp := p
+				cp.Metadata = cloneMap(p.Metadata)
+				princs[p.ID] = &cp
+				continue
+			}
+			m.str("principal", p.ID, "name", &dst.Name, p.Name)
+			// "unknown" is the mapper default, not evidence
+			if p.Trust != "unknown" {
+				if dst.Trust == "unknown" {
+					dst.Trust = ""
+				}
+				m.str("principal", p.ID, "trust_level", &dst.Trust, p.Trust)
+			}
+			m.str("principal", p.ID, "builder", &dst.Builder, p.Builder)
+			m.str("principal", p.ID, "trust_reason", &dst.TrustReason, p.TrustReason)
+			// activity windows widen instead of conflicting
+			md := cloneMap(p.Metadata)
+			if dst.Metadata == nil {
+				dst.Metadata = map[string]string{}
+			}
+			for _, k := range []string{"first_seen", "last_seen"} {
+				if t, ok := graph.ParseTimestamp(md[k]); ok {
+					trackSeen(dst.Metadata, t)
+				}
+				delete(md, k)
+			}
+			m.strMap("principal", p.ID, "metadata", &dst.Metadata, md)
+		}
+		for _, r := range g.Resources {
+			dst, ok := resources[r.ID]
+			if !ok {
+				cp := r
+				cp.Metadata = cloneMap(r.Metadata)
+				resources[r.ID] = &cp
+				continue
+			}
+			m.str("resource", r.ID, "type", &dst.Type, r.Type)
+			m.str("resource", r.ID, "uri", &dst.URI, r.URI)
+			m.str("resource", r.ID, "format", &dst.Format, r.Format)
+			m.str("resource", r.ID, "purl", &dst.PURL, r.PURL)
+			m.strMap("resource", r.ID, "metadata", &dst.Metadata, r.Metadata)
+		}
+		for _, e := range g.Edges {
+			k := e.Source + "|" + e.Relation + "|" + e.Target
+			dst, ok := edges[k]
+			if !ok {
+				cp := e
+				cp.Metadata = cloneMap(e.Metadata)
+				edges[k] = &cp
+				continue
+			}
+			m.strMap("edge", k, "metadata", &dst.Metadata, e.Metadata)
+		}
+	}
+
+	if policy == Record {
+		for _, c := range m.conflicts {
+			var md *map[string]string
+			switch c.Kind {
+			case "artifact":
+				md = &arts[c.ID].Metadata
+			case "step":
+				md = &steps[c.ID].Metadata
+			case "principal":
+				md = &princs[c.ID].Metadata
+			case "resource":
+				md = &resources[c.ID].Metadata
+			case "edge":
+				md = &edges[c.ID].Metadata
+			}
+			if *md == nil {
+				*md = map[string]string{}
+			}
+			key := "conflict." + c.Field
+			if prev, ok := (*md)[key]; ok {
+				(*md)[key] = prev + " | " + c.Other
+			} else {
+				(*md)[key] = c.Other
+			}
+		}
+	}
+
+	out := graph.AstraGraph{}
+	for _, a := range arts {
+		out.Artifacts = append(out.Artifacts, *a)
+	}
+	for _, s := range steps {
+		out.Steps = append(out.Steps, *s)
+	}
+	for _, p := range princs {
+		out.Principals = append(out.Principals, *p)
+	}
+	for _, r := range resources {
+		out.Resources = append(out.Resources, *r)
+	}
+	for _, e := range edges {
+		out.Edges = append(out.Edges, *e)
+	}
+	SortGraph(&out)
+
+	return out, m.conflicts
+}
+
+func sizeString(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
@@ -22,24 +22,36 @@ func cloneMap(in map[string]string) map[string]string {
 
 // normalizeArtifact converts a parser.Item into a typed graph.Artifact.
 // It preserves all attrs in Metadata and additionally extracts Hash/Size when present.
-func normalizeArtifact(it parser.Item) graph.Artifact {
+// sr may override which attrs feed the typed fields.
+func normalizeArtifact(it parser.Item, sr *SourceRules) graph.Artifact {
+	kind := it.Kind
+	if v := pick(it.Attrs, sr.artifactKeys("kind")); v != "" {
+		kind = v
+	}
+	name := it.Label
+	if v := pick(it.Attrs, sr.artifactKeys("name")); v != "" {
+		name = v
+	}
 	a := graph.Artifact{
 		ID:       it.ID,
-		Kind:     normalizeArtifactKind(it.Kind),
-		Name:     it.Label,
-		Version:  extractVersionFromID(it.ID), // explicit version (best-effort)
+		Kind:     normalizeArtifactKind(kind),
+		Name:     name,
 		Metadata: map[string]string{},
 	}
+	applyIdentity(&a)
+	if v := pick(it.Attrs, sr.artifactKeys("version")); v != "" {
+		a.Version = v
+	}
 
 	for k, v := range it.Attrs {
 		a.Metadata[k] = v
 	}
 
 	// Pull typed fields if present
-	if h, ok := it.Attrs["content-hash"]; ok && strings.TrimSpace(h) != "" {
+	if h := pick(it.Attrs, sr.artifactKeys("hash", "content-hash")); h != "" {
 		a.Hash = h
 	}
-	if s, ok := it.Attrs["size"]; ok && strings.TrimSpace(s) != "" {
+	if s := pick(it.Attrs, sr.artifactKeys("size", "size")); s != "" {
 		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
 			a.Size = n
 		}
@@ -58,33 +70,25 @@ func normalizeArtifactKind(k string) string {
 	return k
 }
 
-// extractVersionFromID best-effort parses "@<version>" from IDs like:
-// artifact:gitfile:<slug>@<hash>:path
-// artifact:gitcommit:<slug>@<hash>
-// TODO needs fix, look at the output
-func extractVersionFromID(id string) string {
-	// Find "...@<ver>..."
-	at := strings.LastIndex(id, "@")
-	if at < 0 || at == len(id)-1 {
-		return ""
-	}
-	rest := id[at+1:]
-	// cut at ":" (file path separator) if present
-	if i := strings.Index(rest, ":"); i >= 0 {
-		return rest[:i]
-	}
-	return rest
+// applyIdentity fills Namespace/Version/PURL from the structured artifact ID.
+// The label stays the display name; the parsed name is only used when the
+// parser gave no label.
+func applyIdentity(a *graph.Artifact) {
+	ident, ok := ParseArtifactID(a.ID)
+	if !ok {
+		return
+	}
+	if a.Name == "" {
+		a.Name = ident.Name
+	}
+	a.Namespace = ident.Namespace
+	a.Version = ident.Version
+	a.PURL = ident.PURL()
 }
 
-func normalizeStepCommand(md map[string]string) string {
+func normalizeStepCommand(md map[string]string, sr *SourceRules) string {
 	// Keep stable, queryable command values.
-	if v, ok := md["command"]; ok && strings.TrimSpace(v) != "" {
-		return v
-	}
-	if v, ok := md["label"]; ok && strings.TrimSpace(v) != "" {
-		return v
-	}
-	return ""
+	return pick(md, sr.stepKeys("command", "command", "label"))
 }
 
 func normalizeStepEnviroment(md map[string]string) string {
@@ -95,16 +99,16 @@ func normalizeStepEnviroment(md map[string]string) string {
 	return ""
 }
 
-func normalizeStepArch(md map[string]string) string {
+func normalizeStepArch(md map[string]string, sr *SourceRules) string {
 
 	// Keep it stable; or leave empty
-	if v, ok := md["architecture"]; ok && strings.TrimSpace(v) != "" {
-		return v
-	}
-	return ""
+	return pick(md, sr.stepKeys("architecture", "architecture"))
 }
 
-func normalizeResourceType(r parser.Item) string {
+func normalizeResourceType(r parser.Item, sr *SourceRules) string {
+	if v := pick(r.Attrs, sr.resourceKeys("type")); v != "" {
+		return v
+	}
 	// parser emits Kind="vcs" for git; keep that if present.
 	if strings.TrimSpace(r.Kind) != "" {
 		return r.Kind
@@ -112,22 +116,26 @@ func normalizeResourceType(r parser.Item) string {
 	return ""
 }
 
-func normalizeResourceURI(r parser.Item) string {
-	// If we later store URI in attrs, read it here; otherwise empty.
-	if r.Attrs != nil {
-		if uri, ok := r.Attrs["uri"]; ok && strings.TrimSpace(uri) != "" {
-			return uri
-		}
+func normalizeResourceURI(r parser.Item, sr *SourceRules) string {
+	return pick(r.Attrs, sr.resourceKeys("uri", "uri"))
+}
+
+// normalizeResourcePURL prefers an explicit "purl" attr, else derives one
+// from the resource ID (e.g. buildinfo "<pkg>@<version>" dependencies).
+func normalizeResourcePURL(r parser.Item, sr *SourceRules) string {
+	if p := pick(r.Attrs, sr.resourceKeys("purl", "purl")); p != "" {
+		return p
+	}
+	if ident, ok := ParseArtifactID(r.ID); ok {
+		return ident.PURL()
 	}
 	return ""
 }
 
-func normalizeResourceFormat(r parser.Item) string {
+func normalizeResourceFormat(r parser.Item, sr *SourceRules) string {
 	// Prefer explicit, else infer.
-	if r.Attrs != nil {
-		if f, ok := r.Attrs["format"]; ok && strings.TrimSpace(f) != "" {
-			return f
-		}
+	if f := pick(r.Attrs, sr.resourceKeys("format", "format")); f != "" {
+		return f
 	}
 	// Git parser resource is git.
 	if strings.Contains(strings.ToLower(r.ID), "git") {
@@ -136,19 +144,27 @@ func normalizeResourceFormat(r parser.Item) string {
 	return ""
 }
 
-// normalizeTimestamp returns a string timestamp for current graph.Step schema.
-// If we later switch to int64 timestamps, change this to return int64.
-func normalizeTimestamp(md map[string]string) string {
-	if md == nil {
-		return ""
-	}
-	// Prefer unix seconds in md["time"]
-	if t, ok := md["time"]; ok && strings.TrimSpace(t) != "" {
-		return t
+// normalizeTimestamp returns the raw step time for graph.Step.SetTimestamp.
+// Prefers unix seconds in md["time"], falls back to md["timestamp"].
+func normalizeTimestamp(md map[string]string, sr *SourceRules) string {
+	return pick(md, sr.stepKeys("timestamp", "time", "timestamp"))
+}
+
+// normalizePrincipal returns trust level and builder for a principal.
+// Without rules every principal is "unknown" with no builder.
+func normalizePrincipal(md map[string]string, sr *SourceRules) (trust, builder string) {
+	trust = pick(md, sr.principalKeys("trust_level"))
+	builder = pick(md, sr.principalKeys("builder"))
+	if sr != nil {
+		if trust == "" {
+			trust = sr.Trust
+		}
+		if builder == "" {
+			builder = sr.Builder
+		}
 	}
-	// Fall back if we ever store other keys
-	if t, ok := md["timestamp"]; ok && strings.TrimSpace(t) != "" {
-		return t
+	if trust == "" {
+		trust = "unknown"
 	}
-	return ""
+	return trust, builder
 }
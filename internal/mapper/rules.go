@@ -0,0 +1,213 @@
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/parser"
+)
+
+/*
+Rules lets teams with in-house build logs map their records without
+forking the mapper. Rules are keyed by parser.Mapped.Source; the "*" entry
+applies to every source without its own entry. Example:
+
+	{
+	  "sources": {
+	    "acme-ci": {
+	      "step":      {"command": ["job", "script"], "timestamp": ["started_at"]},
+	      "artifact":  {"hash": ["sha256"]},
+	      "resource":  {"uri": ["runner_url"]},
+	      "principal": {"trust_level": ["verified"]},
+	      "trust": "verified",
+	      "relations": [{"from": "principal", "to": "step", "relation": "triggers"}]
+	    }
+	  }
+	}
+
+Rules files are JSON only; YAML is not accepted. Field lists name the
+record attrs to read, first non-empty wins. They are tried before the
+built-in keys, which still apply when none of the listed attrs is set, so
+a partial rule only adds keys. A field without an entry keeps the built-in
+behaviour.
+*/
+type Rules struct {
+	Sources map[string]SourceRules `json:"sources"`
+}
+
+// SourceRules is the mapping for one Mapped.Source.
+type SourceRules struct {
+	Artifact  map[string][]string `json:"artifact,omitempty"`  // kind|name|version|hash|size
+	Step      map[string][]string `json:"step,omitempty"`      // command|timestamp|architecture
+	Resource  map[string][]string `json:"resource,omitempty"`  // type|uri|format|purl
+	Principal map[string][]string `json:"principal,omitempty"` // name|trust_level|builder
+
+	// Trust is the trust level given to principals that carry no trust attr.
+	Trust string `json:"trust,omitempty"`
+	// Builder is the builder given to principals that carry no builder attr.
+	Builder string `json:"builder,omitempty"`
+
+	Relations []RelationRule `json:"relations,omitempty"`
+	// DefaultRelations=false drops the built-in uses/carries_out/consumes/produces edges.
+	DefaultRelations *bool `json:"default_relations,omitempty"`
+}
+
+// RelationRule adds an edge for every pair of record roles.
+// Roles: principal, step, resource, artifact_in, artifact_out.
+type RelationRule struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+}
+
+var (
+	roles = map[string]bool{"principal": true, "step": true, "resource": true, "artifact_in": true, "artifact_out": true}
+
+	ruleFields = map[string]map[string]bool{
+		"artifact":  {"kind": true, "name": true, "version": true, "hash": true, "size": true},
+		"step":      {"command": true, "timestamp": true, "architecture": true},
+		"resource":  {"type": true, "uri": true, "format": true, "purl": true},
+		"principal": {"name": true, "trust_level": true, "builder": true},
+	}
+)
+
+// LoadRules reads and validates a JSON rules file. Other formats, YAML
+// included, fail to parse.
+func LoadRules(path string) (*Rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("rules %s: %w", path, err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("rules %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Validate rejects unknown fields and relation roles, so typos don't
+// silently fall back to the built-in mapping.
+func (r *Rules) Validate() error {
+	for src, sr := range r.Sources {
+		for kind, fields := range map[string]map[string][]string{
+			"artifact": sr.Artifact, "step": sr.Step, "resource": sr.Resource, "principal": sr.Principal,
+		} {
+			for f := range fields {
+				if !ruleFields[kind][f] {
+					return fmt.Errorf("source %q: unknown %s field %q", src, kind, f)
+				}
+			}
+		}
+		for _, rel := range sr.Relations {
+			if !roles[rel.From] || !roles[rel.To] {
+				return fmt.Errorf("source %q: relation %q: unknown role %q -> %q", src, rel.Relation, rel.From, rel.To)
+			}
+			if strings.TrimSpace(rel.Relation) == "" {
+				return fmt.Errorf("source %q: relation %s -> %s has no name", src, rel.From, rel.To)
+			}
+		}
+	}
+	return nil
+}
+
+// For returns the rules for a source, nil when none apply.
+func (r *Rules) For(source string) *SourceRules {
+	if r == nil {
+		return nil
+	}
+	if sr, ok := r.Sources[source]; ok {
+		return &sr
+	}
+	if sr, ok := r.Sources["*"]; ok {
+		return &sr
+	}
+	return nil
+}
+
+// keys returns the attrs to read for a field: the rule keys first, then the
+// defaults the rule does not already list.
+func (sr *SourceRules) keys(fields map[string][]string, field string, def ...string) []string {
+	k := fields[field]
+	if sr == nil || len(k) == 0 {
+		return def
+	}
+	out := append([]string(nil), k...)
+	for _, d := range def {
+		if !slices.Contains(k, d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func (sr *SourceRules) artifactKeys(field string, def ...string) []string {
+	if sr == nil {
+		return def
+	}
+	return sr.keys(sr.Artifact, field, def...)
+}
+
+func (sr *SourceRules) stepKeys(field string, def ...string) []string {
+	if sr == nil {
+		return def
+	}
+	return sr.keys(sr.Step, field, def...)
+}
+
+func (sr *SourceRules) resourceKeys(field string, def ...string) []string {
+	if sr == nil {
+		return def
+	}
+	return sr.keys(sr.Resource, field, def...)
+}
+
+func (sr *SourceRules) principalKeys(field string, def ...string) []string {
+	if sr == nil {
+		return def
+	}
+	return sr.keys(sr.Principal, field, def...)
+}
+
+func (sr *SourceRules) defaultRelations() bool {
+	return sr == nil || sr.DefaultRelations == nil || *sr.DefaultRelations
+}
+
+// roleIDs returns the node IDs a record plays in a role.
+func roleIDs(rec parser.Record, role string) []string {
+	var ids []string
+	switch role {
+	case "principal":
+		ids = append(ids, rec.Principal.ID)
+	case "step":
+		ids = append(ids, rec.Step.ID)
+	case "resource":
+		for _, it := range rec.Resources {
+			ids = append(ids, it.ID)
+		}
+	case "artifact_in":
+		for _, it := range rec.ArtifactsIn {
+			ids = append(ids, it.ID)
+		}
+	case "artifact_out":
+		for _, it := range rec.ArtifactsOut {
+			ids = append(ids, it.ID)
+		}
+	}
+	return ids
+}
+
+// pick returns the first non-empty attr among keys.
+func pick(attrs map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v, ok := attrs[k]; ok && strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
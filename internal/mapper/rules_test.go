@@ -0,0 +1,66 @@
+package mapper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleKeysAddToDefaults(t *testing.T) {
+	sr := &SourceRules{Step: map[string][]string{"command": {"job", "label"}}}
+	for _, tc := range []struct {
+		name string
+		md   map[string]string
+		want string
+	}{
+		{"rule key first", map[string]string{"job": "make", "command": "go build"}, "make"},
+		{"default when rule attr missing", map[string]string{"command": "go build"}, "go build"},
+		{"listed default keeps rule order", map[string]string{"label": "build"}, "build"},
+		{"nothing set", map[string]string{"other": "x"}, ""},
+	} {
+		if got := normalizeStepCommand(tc.md, sr); got != tc.want {
+			t.Errorf("%s: command = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if got := sr.stepKeys("command", "command", "label"); len(got) != 3 || got[0] != "job" || got[1] != "label" || got[2] != "command" {
+		t.Errorf("stepKeys = %v, want [job label command]", got)
+	}
+	var none *SourceRules
+	if got := none.stepKeys("command", "command", "label"); len(got) != 2 {
+		t.Errorf("nil rules stepKeys = %v, want the defaults", got)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	r, err := LoadRules(write("ok.json", `{"sources":{"acme-ci":{"step":{"command":["job"]},"trust":"verified"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr := r.For("acme-ci"); sr == nil || sr.Trust != "verified" {
+		t.Errorf("For(acme-ci) = %+v", sr)
+	}
+	if sr := r.For("other"); sr != nil {
+		t.Errorf("For(other) = %+v, want nil without a \"*\" entry", sr)
+	}
+
+	for name, body := range map[string]string{
+		"yaml.yaml":     "sources:\n  acme-ci:\n    trust: verified\n",
+		"field.json":    `{"sources":{"acme-ci":{"step":{"cmd":["job"]}}}}`,
+		"role.json":     `{"sources":{"acme-ci":{"relations":[{"from":"job","to":"step","relation":"runs"}]}}}`,
+		"relation.json": `{"sources":{"acme-ci":{"relations":[{"from":"principal","to":"step","relation":" "}]}}}`,
+	} {
+		if _, err := LoadRules(write(name, body)); err == nil {
+			t.Errorf("LoadRules(%s) succeeded, want an error", name)
+		}
+	}
+}
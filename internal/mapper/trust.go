@@ -0,0 +1,191 @@
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Evidence kinds a TrustRule can match on.
+const (
+	EvidenceTrustedBuilder    = "trusted_builder"    // SLSA builder id (metadata builder_id) or Builder in Values
+	EvidenceSignatureVerified = "signature_verified" // metadata signature=verified|good
+	EvidenceMaintainer        = "maintainer"         // principal ID or email in Values
+	EvidenceBuilder           = "builder"            // Builder field in Values
+	EvidenceDeclared          = "declared"           // trust already set by the parser or mapping rules
+	EvidenceSigned            = "signed"             // a signing key is recorded but not verified (and not signature=bad)
+	EvidenceFirstSeenWithin   = "first_seen_within"  // first activity within Days of the newest activity in the graph
+)
+
+/*
+TrustPolicy derives Principal.Trust from evidence instead of a constant.
+Rules are evaluated in order and the first match decides; principals no
+rule matches get Default. Every decision is recorded in
+Principal.TrustReason. Example:
+
+	{
+	  "rules": [
+	    {"evidence": "trusted_builder", "values": ["https://github.com/actions/runner/github-hosted"], "trust": "verified"},
+	    {"evidence": "signature_verified", "trust": "verified"},
+	    {"evidence": "maintainer", "values": ["alice@example.org"], "trust": "trusted"},
+	    {"evidence": "first_seen_within", "days": 90, "trust": "new"},
+	    {"evidence": "signed", "trust": "signed"}
+	  ],
+	  "default": "unknown"
+	}
+*/
+type TrustPolicy struct {
+	Rules   []TrustRule `json:"rules"`
+	Default string      `json:"default"`
+}
+
+type TrustRule struct {
+	Evidence string   `json:"evidence"`
+	Values   []string `json:"values,omitempty"`
+	Days     int      `json:"days,omitempty"`
+	Trust    string   `json:"trust"`
+}
+
+// DefaultTrustPolicy only uses evidence present in the graph itself.
+func DefaultTrustPolicy() TrustPolicy {
+	return TrustPolicy{
+		Rules: []TrustRule{
+			{Evidence: EvidenceSignatureVerified, Trust: "verified"},
+			{Evidence: EvidenceDeclared},
+			{Evidence: EvidenceFirstSeenWithin, Days: 90, Trust: "new"},
+			{Evidence: EvidenceSigned, Trust: "signed"},
+		},
+		Default: "unknown",
+	}
+}
+
+// LoadTrustPolicy reads a JSON trust policy.
+func LoadTrustPolicy(path string) (TrustPolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return TrustPolicy{}, err
+	}
+	var p TrustPolicy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return TrustPolicy{}, fmt.Errorf("trust policy %s: %w", path, err)
+	}
+	for i, r := range p.Rules {
+		switch r.Evidence {
+		case EvidenceTrustedBuilder, EvidenceMaintainer, EvidenceBuilder:
+			if len(r.Values) == 0 {
+				return TrustPolicy{}, fmt.Errorf("trust policy %s: rule %d (%s) has no values", path, i, r.Evidence)
+			}
+		case EvidenceFirstSeenWithin:
+			if r.Days <= 0 {
+				return TrustPolicy{}, fmt.Errorf("trust policy %s: rule %d (%s) needs days > 0", path, i, r.Evidence)
+			}
+		case EvidenceSignatureVerified, EvidenceDeclared, EvidenceSigned:
+		default:
+			return TrustPolicy{}, fmt.Errorf("trust policy %s: rule %d: unknown evidence %q", path, i, r.Evidence)
+		}
+		if r.Trust == "" && r.Evidence != EvidenceDeclared {
+			return TrustPolicy{}, fmt.Errorf("trust policy %s: rule %d (%s) has no trust level", path, i, r.Evidence)
+		}
+	}
+	if p.Default == "" {
+		p.Default = "unknown"
+	}
+	return p, nil
+}
+
+// DeriveTrust sets Trust and TrustReason on every principal of g.
+// Recency is measured against the newest last_seen in the graph, not the
+// wall clock, so the result is reproducible.
+func DeriveTrust(g *graph.AstraGraph, p TrustPolicy) {
+	var newest time.Time
+	for _, pr := range g.Principals {
+		if t, ok := graph.ParseTimestamp(pr.Metadata["last_seen"]); ok && t.After(newest) {
+			newest = t
+		}
+	}
+	for i := range g.Principals {
+		pr := &g.Principals[i]
+		trust, reason := p.decide(*pr, newest)
+		pr.Trust, pr.TrustReason = trust, reason
+	}
+}
+
+func (p TrustPolicy) decide(pr graph.Principal, newest time.Time) (string, string) {
+	for _, r := range p.Rules {
+		if reason, ok := r.match(pr, newest); ok {
+			trust := r.Trust
+			if r.Evidence == EvidenceDeclared && trust == "" {
+				trust = pr.Trust
+			}
+			return trust, reason
+		}
+	}
+	return p.Default, "no trust evidence"
+}
+
+// match reports whether the principal carries the rule's evidence and why.
+func (r TrustRule) match(pr graph.Principal, newest time.Time) (string, bool) {
+	md := pr.Metadata
+	switch r.Evidence {
+	case EvidenceTrustedBuilder:
+		for _, id := range []string{md["builder_id"], pr.Builder} {
+			if id != "" && containsFold(r.Values, id) {
+				return fmt.Sprintf("builder %s is a trusted builder", id), true
+			}
+		}
+	case EvidenceSignatureVerified:
+		switch strings.ToLower(md["signature"]) {
+		case "verified", "good", "valid":
+			if k := graph.SigningKey(md); k != "" {
+				return fmt.Sprintf("signature by key %s verified", k), true
+			}
+			return "signature verified", true
+		}
+	case EvidenceMaintainer:
+		for _, id := range []string{pr.ID, md["email"], strings.TrimPrefix(pr.ID, "principal:")} {
+			if id != "" && containsFold(r.Values, id) {
+				return fmt.Sprintf("%s is on the maintainer allowlist", id), true
+			}
+		}
+	case EvidenceBuilder:
+		if pr.Builder != "" && containsFold(r.Values, pr.Builder) {
+			return fmt.Sprintf("builder %q is on the builder allowlist", pr.Builder), true
+		}
+	case EvidenceDeclared:
+		if pr.Trust != "" && pr.Trust != "unknown" {
+			if pr.TrustReason != "" {
+				return pr.TrustReason, true
+			}
+			return fmt.Sprintf("trust %q declared by source", pr.Trust), true
+		}
+	case EvidenceSigned:
+		if strings.EqualFold(md["signature"], "bad") {
+			return "", false
+		}
+		if k := graph.SigningKey(md); k != "" {
+			return fmt.Sprintf("signed with key %s (signature not verified)", k), true
+		}
+	case EvidenceFirstSeenWithin:
+		first, ok := graph.ParseTimestamp(md["first_seen"])
+		if !ok || newest.IsZero() {
+			return "", false
+		}
+		if newest.Sub(first) <= time.Duration(r.Days)*24*time.Hour {
+			return fmt.Sprintf("first seen %s, within %d days of latest activity", first.Format("2006-01-02"), r.Days), true
+		}
+	}
+	return "", false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
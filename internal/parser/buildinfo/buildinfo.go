@@ -1,7 +1,9 @@
-package parser
+// Package buildinfo parses Debian .buildinfo files.
+package buildinfo
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,12 +14,14 @@ import (
 
 	"github.com/ProtonMail/gopenpgp/v3/crypto"
 	graph "github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+	parse "github.com/abuishgair/astra/internal/parser"
 )
 
 type BuildinfoParser struct{}
 
-func (p *BuildinfoParser) Parse(path string) (Mapped, error) {
-	graph, err := parseBuildinfo(path)
+func (p *BuildinfoParser) Parse(path string) (parse.Mapped, error) {
+	graph, err := parseBuildinfo(path, nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -41,21 +45,49 @@ func (p *BuildinfoParser) Parse(path string) (Mapped, error) {
 	}
 
 	fmt.Printf("Graph saved to %s\n", outPath)
-	n := Mapped{Source: "build-info", NormalizedAt: time.Now().Unix()}
+	n := parse.Mapped{Source: "build-info", NormalizedAt: time.Now().Unix()}
 	return n, nil
 }
 
-func parseBuildinfo(path string) (*graph.AstraGraph, error) {
-	file, err := os.Open(path)
+/*
+ParseGraph parses a .buildinfo file into a single-build graph: one
+dpkg-buildpackage step, its .deb outputs, build dependencies and origin.
+The origin's trust is derived from the file's signature with the default
+trust policy: "verified" if keys is given and the signature checks out
+against it, "signed" for a signature that was not checked, otherwise
+"unknown". keys may be nil.
+*/
+func ParseGraph(path string, keys *crypto.KeyRing) (*graph.AstraGraph, error) {
+	return parseBuildinfo(path, keys)
+}
+
+// LoadKeyring reads an armored public key or a binary keyring such as
+// /usr/share/keyrings/debian-keyring.gpg.
+func LoadKeyring(path string) (*crypto.KeyRing, error) {
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		return crypto.NewKeyRingFromBinary(b)
+	}
+	key, err := crypto.NewKeyFromArmored(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return crypto.NewKeyRing(key)
+}
 
-	scanner := bufio.NewScanner(file)
+func parseBuildinfo(path string, keys *crypto.KeyRing) (*graph.AstraGraph, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
 	astra_graph := &graph.AstraGraph{}
 
-	var source, version, buildArch, buildDate, buildOrigin string
+	var source, version, buildArch, buildDate, buildOrigin, buildPath string
 	var outputs []graph.Artifact
 	var pgpLines []string
 	var keyIDs []string
@@ -68,6 +100,13 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		// Multi-line fields continue on indented lines; the next field
+		// header, a blank line or a PGP armor line ends them. .buildinfo
+		// files have no blank line between fields.
+		if line == "" || (line[0] != ' ' && line[0] != '\t') {
+			envSection, dependsSection, outputSection = false, false, false
+		}
+
 		switch {
 		case strings.HasPrefix(line, "Source:"):
 			source = strings.TrimSpace(strings.TrimPrefix(line, "Source:"))
@@ -79,6 +118,8 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 			buildDate = strings.TrimSpace(strings.TrimPrefix(line, "Build-Date:"))
 		case strings.HasPrefix(line, "Build-Origin:"):
 			buildOrigin = strings.TrimSpace(strings.TrimPrefix(line, "Build-Origin:"))
+		case strings.HasPrefix(line, "Build-Path:"):
+			buildPath = strings.TrimSpace(strings.TrimPrefix(line, "Build-Path:"))
 		case strings.HasPrefix(line, "Checksums-Sha256:"):
 			outputSection = true
 		case strings.HasPrefix(line, "Installed-Build-Depends:"):
@@ -88,12 +129,6 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 			continue // Skip the header line
 		case strings.HasPrefix(line, "-----BEGIN PGP SIGNATURE-----"):
 			pgpSection = true
-		case outputSection && strings.TrimSpace(line) == "":
-			outputSection = false
-		case dependsSection && strings.TrimSpace(line) == "":
-			dependsSection = false
-		case envSection && strings.TrimSpace(line) == "":
-			envSection = false
 		case strings.HasPrefix(line, "-----END PGP SIGNATURE-----"):
 			pgpSection = false
 		}
@@ -140,12 +175,6 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 			}
 		} else if envSection {
 			trimmed := strings.TrimSpace(line)
-			// Exit early if next section starts or line is empty
-			if trimmed == "" || strings.HasPrefix(trimmed, "-----BEGIN") || strings.Contains(trimmed, ":") {
-				envSection = false
-				continue
-			}
-
 			// Only parse lines that look like env vars (contain =)
 			if strings.Contains(trimmed, "=") {
 				parts := strings.SplitN(trimmed, "=", 2)
@@ -158,6 +187,13 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 		}
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if source == "" || version == "" {
+		return nil, fmt.Errorf("%s: missing Source or Version field, not a .buildinfo file", path)
+	}
+
 	stepID := fmt.Sprintf("build-%s@%s", source, version)
 	outputIDs := []string{}
 	for _, a := range outputs {
@@ -178,13 +214,19 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 	})
 	resourceIDs = append(resourceIDs, tarball)
 
-	astra_graph.Steps = append(astra_graph.Steps, graph.Step{
+	step := graph.Step{
 		ID:          stepID,
 		Command:     "dpkg-buildpackage",
-		Timestamp:   buildDate,
 		Arch:        buildArch,
 		Environment: env,
-	})
+		Metadata: map[string]string{
+			"source":     source,
+			"version":    version,
+			"build_path": buildPath,
+		},
+	}
+	step.SetTimestamp(buildDate) // Build-Date is RFC 2822
+	astra_graph.Steps = append(astra_graph.Steps, step)
 
 	if len(pgpLines) > 0 {
 		pgpText := strings.Join(pgpLines, "\n")
@@ -197,20 +239,51 @@ func parseBuildinfo(path string) (*graph.AstraGraph, error) {
 	}
 
 	principal := graph.Principal{
-		ID:      buildOrigin,
-		Trust:   "signed",
-		Builder: "Debian Build Infrastructure",
+		ID:       buildOrigin,
+		Builder:  "Debian Build Infrastructure",
+		Metadata: map[string]string{},
+	}
+	if len(pgpLines) > 0 {
+		principal.Metadata["signature"] = verify(raw, keys)
 	}
 	if len(keyIDs) > 0 {
-		principal.Metadata = map[string]string{
-			"pgp_key_id": keyIDs[0],
-		}
+		principal.Metadata["pgp_key_id"] = keyIDs[0]
 	}
 	astra_graph.Principals = append(astra_graph.Principals, principal)
+	mapper.DeriveTrust(astra_graph, mapper.DefaultTrustPolicy())
+
+	// the edges impact, vulnerability and risk analysis follow: build
+	// dependencies and sources carry out the build, which produces the .debs
+	for _, id := range resourceIDs {
+		astra_graph.Edges = append(astra_graph.Edges, graph.Edge{Source: id, Target: stepID, Relation: "carries_out"})
+	}
+	for _, id := range outputIDs {
+		astra_graph.Edges = append(astra_graph.Edges, graph.Edge{Source: stepID, Target: id, Relation: "produces"})
+	}
+	if buildOrigin != "" {
+		astra_graph.Edges = append(astra_graph.Edges, graph.Edge{Source: buildOrigin, Target: stepID, Relation: "performs"})
+	}
 
 	return astra_graph, nil
 }
 
+// verify checks a clearsigned file against keys: "verified", "bad" or,
+// without keys, "unverified".
+func verify(clearsigned []byte, keys *crypto.KeyRing) string {
+	if keys == nil {
+		return "unverified"
+	}
+	v, err := crypto.PGP().Verify().VerificationKeys(keys).New()
+	if err != nil {
+		return "unverified"
+	}
+	res, err := v.VerifyCleartext(clearsigned)
+	if err != nil || res.SignatureError() != nil {
+		return "bad"
+	}
+	return "verified"
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
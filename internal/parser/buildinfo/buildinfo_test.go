@@ -0,0 +1,161 @@
+package buildinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+)
+
+const hello = `Format: 1.0
+Source: hello
+Binary: hello
+Architecture: amd64
+Version: 2.10-3
+Checksums-Sha256:
+ 5b2d0b7e1c8b0f1e 53204 hello_2.10-3_amd64.deb
+Build-Origin: Debian
+Build-Architecture: amd64
+Build-Date: Mon, 01 Jan 2024 12:00:00 +0000
+Build-Path: /build/reproducible-path/hello-2.10
+Installed-Build-Depends:
+ autoconf (= 2.71-3),
+ gcc-12 (= 12.2.0-14),
+ libc6 (= 2.36-9+deb12u4)
+Environment:
+ DEB_BUILD_OPTIONS="parallel=4"
+ LANG="C.UTF-8"
+ PATH="/usr/bin:/bin"
+ SOURCE_DATE_EPOCH="1700000000"
+`
+
+func write(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParseGraph(t *testing.T) {
+	g, err := ParseGraph(write(t, "hello.buildinfo", hello), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Steps) != 1 {
+		t.Fatalf("%d steps, want 1", len(g.Steps))
+	}
+	st := g.Steps[0]
+	if st.ID != "build-hello@2.10-3" || st.Timestamp != "2024-01-01T12:00:00Z" {
+		t.Errorf("step %s at %q", st.ID, st.Timestamp)
+	}
+	wantEnv := map[string]string{
+		"DEB_BUILD_OPTIONS": "parallel=4",
+		"LANG":              "C.UTF-8",
+		"PATH":              "/usr/bin:/bin",
+		"SOURCE_DATE_EPOCH": "1700000000",
+	}
+	if len(st.Environment) != len(wantEnv) {
+		t.Errorf("environment %v, want %v", st.Environment, wantEnv)
+	}
+	for k, v := range wantEnv {
+		if st.Environment[k] != v {
+			t.Errorf("environment %s = %q, want %q", k, st.Environment[k], v)
+		}
+	}
+
+	deps := map[string]bool{}
+	for _, r := range g.Resources {
+		if r.Type == "build-dependency" {
+			deps[r.ID] = true
+		}
+	}
+	for _, id := range []string{"autoconf@2.71-3", "gcc-12@12.2.0-14", "libc6@2.36-9+deb12u4"} {
+		if !deps[id] {
+			t.Errorf("missing build dependency %s in %v", id, deps)
+		}
+	}
+	if len(deps) != 3 {
+		t.Errorf("%d build dependencies, want 3: %v", len(deps), deps)
+	}
+	if len(g.Artifacts) != 1 || g.Artifacts[0].Hash != "5b2d0b7e1c8b0f1e" || g.Artifacts[0].Size != 53204 {
+		t.Errorf("outputs %+v", g.Artifacts)
+	}
+
+	rels := map[string]int{}
+	for _, e := range g.Edges {
+		rels[e.Relation]++
+	}
+	// three dependencies and the orig tarball, one .deb, one origin
+	if rels["carries_out"] != 4 || rels["produces"] != 1 || rels["performs"] != 1 || len(g.Edges) != 6 {
+		t.Errorf("edges %v", rels)
+	}
+}
+
+func TestParseGraphRejectsOtherFiles(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":      "",
+		"no version": "Source: hello\n",
+		"changelog":  "hello (2.10-3) unstable; urgency=medium\n",
+	} {
+		if _, err := ParseGraph(write(t, "x.buildinfo", content), nil); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestParseGraphTrust(t *testing.T) {
+	pgp := crypto.PGP()
+	key := func() *crypto.Key {
+		k, err := pgp.KeyGeneration().AddUserId("buildd", "buildd@example.org").New().GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	signer, other := key(), key()
+	ring := func(k *crypto.Key) *crypto.KeyRing {
+		pub, err := k.ToPublic()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := crypto.NewKeyRing(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	sh, err := pgp.Sign().SigningKey(signer).New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := sh.SignCleartext([]byte(hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, content string
+		keys          *crypto.KeyRing
+		trust, sig    string
+	}{
+		{"unsigned", hello, nil, "unknown", ""},
+		{"not checked", string(signed), nil, "signed", "unverified"},
+		{"good", string(signed), ring(signer), "verified", "verified"},
+		{"wrong key", string(signed), ring(other), "unknown", "bad"},
+	} {
+		g, err := ParseGraph(write(t, "hello.buildinfo", tc.content), tc.keys)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		p := g.Principals[0]
+		if p.Trust != tc.trust || p.Metadata["signature"] != tc.sig {
+			t.Errorf("%s: trust %q signature %q (%s), want %q %q", tc.name, p.Trust, p.Metadata["signature"], p.TrustReason, tc.trust, tc.sig)
+		}
+		if tc.sig != "" && p.Metadata["pgp_key_id"] == "" {
+			t.Errorf("%s: no pgp_key_id", tc.name)
+		}
+	}
+}
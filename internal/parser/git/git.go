@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -17,6 +18,22 @@ import (
 
 type GitParser struct{}
 
+// signatureKeyID returns the issuer key ID of an armored commit signature,
+// or "" if the commit is unsigned or the signature cannot be read.
+func signatureKeyID(armored string) string {
+	if strings.TrimSpace(armored) == "" {
+		return ""
+	}
+	msg, err := crypto.NewPGPMessageFromArmored(armored)
+	if err != nil {
+		return ""
+	}
+	if ids, ok := msg.HexSignatureKeyIDs(); ok && len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
 func MakeArtifactID(repoURL string, commitHash, filePath string) string {
 	repoSlug := getRepoSlug(repoURL)
 	return fmt.Sprintf("artifact:gitfile:%s@%s:%s", repoSlug, commitHash, filePath)
@@ -286,15 +303,20 @@ func (p *GitParser) Parse(repoURL string) (Mapped, error) {
 		}
 
 		//add the commit as output artifact
+		commitAttrs := map[string]string{
+			"message": strings.TrimSpace(c.Message),
+			"author":  c.Author.Email,
+			"time":    strconv.FormatInt(c.Author.When.Unix(), 10), //TODO check format consistency
+			"tz":      c.Author.When.Format("-0700"),
+		}
+		if key := signatureKeyID(c.PGPSignature); key != "" {
+			commitAttrs["signing_key"] = key
+		}
 		rec.ArtifactsOut = append(rec.ArtifactsOut, Item{
 			ID:    MakeCommitArtifactID(remoteURL, c.Hash.String()),
 			Label: c.Hash.String(),
 			Kind:  "git-commit",
-			Attrs: map[string]string{
-				"message": strings.TrimSpace(c.Message),
-				"author":  c.Author.Email,
-				"time":    strconv.FormatInt(c.Author.When.Unix(), 10), //TODO check format consistency
-			},
+			Attrs: commitAttrs,
 		})
 
 		// ArtifactsOut (after versions)
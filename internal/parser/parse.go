@@ -1,5 +1,12 @@
 package parser
 
+import "encoding/json"
+
+// SchemaVersion is the version of the Mapped JSON format written by this
+// build; files without schema_version count as 0. Version 1 only adds the
+// field. See internal/schema for migrations.
+const SchemaVersion = 1
+
 // Parser is the interface every parser must satisfy
 type Parser interface {
 	Parse(path string) (Mapped, error)
@@ -21,7 +28,17 @@ type Record struct {
 }
 
 type Mapped struct {
-	Mapped       []Record `json:"mapped"`
-	Source       string   `json:"source"`
-	NormalizedAt int64    `json:"normalized_at"`
+	SchemaVersion int      `json:"schema_version"`
+	Mapped        []Record `json:"mapped"`
+	Source        string   `json:"source"`
+	NormalizedAt  int64    `json:"normalized_at"`
+}
+
+// MarshalJSON stamps Mapped values built in memory with SchemaVersion.
+func (m Mapped) MarshalJSON() ([]byte, error) {
+	type plain Mapped
+	if m.SchemaVersion == 0 {
+		m.SchemaVersion = SchemaVersion
+	}
+	return json.Marshal(plain(m))
 }
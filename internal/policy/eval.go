@@ -0,0 +1,240 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+type Violation struct {
+	Rule        string `json:"rule"`
+	Severity    string `json:"severity"`
+	Node        string `json:"node"`
+	Description string `json:"description,omitempty"`
+	Failed      string `json:"failed"`
+}
+
+type Result struct {
+	Rules      int         `json:"rules"`
+	Checked    int         `json:"checked"` // (rule, node) pairs evaluated
+	Violations []Violation `json:"violations"`
+}
+
+// Errors counts violations with severity error; these fail astra check.
+func (r Result) Errors() int {
+	n := 0
+	for _, v := range r.Violations {
+		if v.Severity == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+type evaluator struct {
+	ix    *graph.Index
+	attrs map[string]map[string]string
+}
+
+// Evaluate runs every rule of p (compiled) against g.
+func Evaluate(p *Policy, g graph.AstraGraph) Result {
+	ev := &evaluator{ix: graph.NewIndex(g), attrs: Attributes(g)}
+	res := Result{Rules: len(p.Rules)}
+	for _, r := range p.Rules {
+		for _, id := range ev.ix.IDs {
+			if !typeMatches(ev.ix.Types[id], r.For) || !ev.all(id, r.Where) {
+				continue
+			}
+			res.Checked++
+			for _, c := range r.Require {
+				if !ev.eval(id, c) {
+					res.Violations = append(res.Violations, Violation{
+						Rule:        r.ID,
+						Severity:    r.Severity,
+						Node:        id,
+						Description: r.Description,
+						Failed:      c.String(),
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(res.Violations, func(i, j int) bool {
+		if res.Violations[i].Rule != res.Violations[j].Rule {
+			return res.Violations[i].Rule < res.Violations[j].Rule
+		}
+		return res.Violations[i].Node < res.Violations[j].Node
+	})
+	return res
+}
+
+func (ev *evaluator) all(id string, cs []Condition) bool {
+	for _, c := range cs {
+		if !ev.eval(id, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ev *evaluator) eval(id string, c Condition) bool {
+	switch {
+	case c.Field != "":
+		return c.test(ev.attrs[id])
+	case c.Relation != "":
+		n := 0
+		for _, nb := range ev.neighbours(id, c.Relation, c.Direction) {
+			if typeMatches(ev.ix.Types[nb], c.Type) && ev.all(nb, c.Where) {
+				n++
+			}
+		}
+		if c.Min != nil && n < *c.Min {
+			return false
+		}
+		if c.Max != nil && n > *c.Max {
+			return false
+		}
+		return true
+	case len(c.Any) > 0:
+		for _, x := range c.Any {
+			if ev.eval(id, x) {
+				return true
+			}
+		}
+		return false
+	case c.Not != nil:
+		return !ev.eval(id, *c.Not)
+	}
+	return false
+}
+
+// neighbours returns the distinct nodes linked to id by relation ("*" for any).
+func (ev *evaluator) neighbours(id, rel, dir string) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(n string) {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	if dir != "in" {
+		for _, e := range ev.ix.Out[id] {
+			if rel == "*" || e.Relation == rel {
+				add(e.Target)
+			}
+		}
+	}
+	if dir != "out" {
+		for _, e := range ev.ix.In[id] {
+			if rel == "*" || e.Relation == rel {
+				add(e.Source)
+			}
+		}
+	}
+	return out
+}
+
+func (c Condition) test(attrs map[string]string) bool {
+	v, ok := attrs[c.Field]
+	switch c.Op {
+	case "exists":
+		return ok && v != ""
+	case "empty":
+		return !ok || v == ""
+	case "eq":
+		return v == valueString(c.Value)
+	case "ne":
+		return v != valueString(c.Value)
+	case "in":
+		return inList(v, c.Value)
+	case "not_in":
+		return !inList(v, c.Value)
+	case "prefix":
+		return strings.HasPrefix(v, valueString(c.Value))
+	case "suffix":
+		return strings.HasSuffix(v, valueString(c.Value))
+	case "contains":
+		return strings.Contains(v, valueString(c.Value))
+	case "matches":
+		return c.re != nil && c.re.MatchString(v)
+	}
+	return false
+}
+
+func valueString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func inList(v string, list any) bool {
+	items, ok := list.([]any)
+	if !ok {
+		return v == valueString(list)
+	}
+	for _, it := range items {
+		if v == valueString(it) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeMatches(nodeType, want string) bool {
+	return want == "" || want == "any" || graph.MatchType(nodeType, want)
+}
+
+// Attributes flattens every node into the field names rules refer to.
+func Attributes(g graph.AstraGraph) map[string]map[string]string {
+	out := map[string]map[string]string{}
+	put := func(id, typ string, fields map[string]string, md map[string]string, env map[string]string) {
+		a := map[string]string{"id": id, "node_type": typ}
+		for k, v := range fields {
+			a[k] = v
+		}
+		for k, v := range md {
+			a["metadata."+k] = v
+		}
+		for k, v := range env {
+			a["environment."+k] = v
+		}
+		out[id] = a
+	}
+	for _, n := range g.Artifacts {
+		size := ""
+		if n.Size != 0 {
+			size = strconv.FormatInt(n.Size, 10)
+		}
+		put(n.ID, graph.TypeArtifact, map[string]string{
+			"kind": n.Kind, "name": n.Name, "namespace": n.Namespace, "version": n.Version,
+			"purl": n.PURL, "hash": n.Hash, "size": size,
+		}, n.Metadata, nil)
+	}
+	for _, n := range g.Steps {
+		put(n.ID, graph.TypeStep, map[string]string{
+			"command": n.Command, "timestamp": n.Timestamp, "architecture": n.Arch,
+		}, n.Metadata, n.Environment)
+	}
+	for _, n := range g.Principals {
+		put(n.ID, graph.TypePrincipal, map[string]string{
+			"name": n.Name, "trust_level": n.Trust, "trust_reason": n.TrustReason, "builder": n.Builder,
+		}, n.Metadata, nil)
+	}
+	for _, n := range g.Resources {
+		put(n.ID, graph.TypeResource, map[string]string{
+			"type": n.Type, "uri": n.URI, "format": n.Format, "purl": n.PURL,
+		}, n.Metadata, nil)
+	}
+	return out
+}
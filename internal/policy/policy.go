@@ -0,0 +1,284 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/*
+Policy is a set of supply-chain rules evaluated over an AstraGraph.
+
+A rule selects nodes of one type ("for") that satisfy every "where"
+condition, and reports a violation for each selected node that fails a
+"require" condition. Conditions either test a field of the node or count
+its neighbours over a relation:
+
+	{
+	  "rules": [
+	    {
+	      "id": "release-needs-verified-principal",
+	      "description": "steps producing a release artifact need a verified principal",
+	      "for": "step",
+	      "where": [
+	        {"relation": "produces", "direction": "out", "type": "artifact",
+	         "where": [{"field": "kind", "op": "eq", "value": "binary"}], "min": 1}
+	      ],
+	      "require": [
+	        {"relation": "performs", "direction": "in", "type": "principal",
+	         "where": [{"field": "trust_level", "op": "eq", "value": "verified"}], "min": 1}
+	      ]
+	    },
+	    {
+	      "id": "workflow-change-signed",
+	      "for": "step",
+	      "where": [{"relation": "produces", "direction": "out", "type": "artifact",
+	                 "where": [{"field": "name", "op": "prefix", "value": ".github/workflows/"}], "min": 1}],
+	      "require": [{"relation": "produces", "direction": "out", "type": "artifact",
+	                   "where": [{"field": "kind", "op": "eq", "value": "git-commit"},
+	                             {"field": "metadata.signing_key", "op": "exists"}], "min": 1}]
+	    }
+	  ]
+	}
+
+Fields are the JSON names of the node (id, kind, name, command, uri,
+trust_level ...) plus node_type, "metadata.<key>" and "environment.<key>".
+Relation "*" matches any relation.
+Ops: eq, ne, in, not_in, prefix, suffix, contains, matches (regexp),
+exists, empty. "any" is an OR of conditions, "not" negates one. where,
+type, direction, min and max belong to relation conditions, op and value
+to field conditions; anything else is rejected when the policy loads.
+
+Graphs mapped from git have one principal per commit step: reviews are
+not recorded there, so a rule like {"relation": "performs", "min": 2} on
+commit steps always fails until review data comes from another source.
+*/
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Severity    string      `json:"severity,omitempty"` // error (default) | warning
+	For         string      `json:"for"`                // artifact|step|principal|resource|any
+	Where       []Condition `json:"where,omitempty"`
+	Require     []Condition `json:"require"`
+}
+
+type Condition struct {
+	// field test
+	Field string `json:"field,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Value any    `json:"value,omitempty"`
+
+	// neighbour count: nodes reached over Relation that satisfy Where
+	Relation  string      `json:"relation,omitempty"`
+	Direction string      `json:"direction,omitempty"` // out|in|both (default both)
+	Type      string      `json:"type,omitempty"`
+	Where     []Condition `json:"where,omitempty"`
+	Min       *int        `json:"min,omitempty"`
+	Max       *int        `json:"max,omitempty"`
+
+	// combinators
+	Any []Condition `json:"any,omitempty"`
+	Not *Condition  `json:"not,omitempty"`
+
+	re *regexp.Regexp
+}
+
+var ops = map[string]bool{
+	"eq": true, "ne": true, "in": true, "not_in": true, "prefix": true, "suffix": true,
+	"contains": true, "matches": true, "exists": true, "empty": true,
+}
+
+var types = map[string]bool{"artifact": true, "step": true, "principal": true, "resource": true, "any": true, "": true}
+
+// Load reads and compiles a JSON policy file.
+func Load(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	if err := p.Compile(); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Compile validates rules and compiles regular expressions.
+func (p *Policy) Compile() error {
+	ids := map[string]bool{}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.ID == "" {
+			return fmt.Errorf("rule %d has no id", i)
+		}
+		if ids[r.ID] {
+			return fmt.Errorf("duplicate rule id %q", r.ID)
+		}
+		ids[r.ID] = true
+		r.For = strings.ToLower(r.For)
+		if !types[r.For] {
+			return fmt.Errorf("rule %s: unknown node type %q", r.ID, r.For)
+		}
+		switch r.Severity {
+		case "":
+			r.Severity = "error"
+		case "error", "warning":
+		default:
+			return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+		}
+		if len(r.Require) == 0 {
+			return fmt.Errorf("rule %s has no require conditions", r.ID)
+		}
+		for j := range r.Where {
+			if err := r.Where[j].compile(); err != nil {
+				return fmt.Errorf("rule %s: where: %w", r.ID, err)
+			}
+		}
+		for j := range r.Require {
+			if err := r.Require[j].compile(); err != nil {
+				return fmt.Errorf("rule %s: require: %w", r.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Condition) compile() error {
+	set := 0
+	if c.Field != "" {
+		set++
+	}
+	if c.Relation != "" {
+		set++
+	}
+	if len(c.Any) > 0 {
+		set++
+	}
+	if c.Not != nil {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("condition needs exactly one of field, relation, any, not")
+	}
+	// keys of another kind of condition would be silently ignored
+	if c.Relation == "" && (len(c.Where) > 0 || c.Type != "" || c.Direction != "" || c.Min != nil || c.Max != nil) {
+		return fmt.Errorf("%s: where, type, direction, min and max only apply to relation conditions", c.kind())
+	}
+	if c.Field == "" && (c.Op != "" || c.Value != nil) {
+		return fmt.Errorf("%s: op and value only apply to field conditions", c.kind())
+	}
+
+	switch {
+	case c.Field != "":
+		if !ops[c.Op] {
+			return fmt.Errorf("field %s: unknown op %q", c.Field, c.Op)
+		}
+		if c.Op == "matches" {
+			s, ok := c.Value.(string)
+			if !ok {
+				return fmt.Errorf("field %s: matches needs a string pattern", c.Field)
+			}
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", c.Field, err)
+			}
+			c.re = re
+		}
+	case c.Relation != "":
+		switch c.Direction {
+		case "", "both", "in", "out":
+		default:
+			return fmt.Errorf("relation %s: unknown direction %q", c.Relation, c.Direction)
+		}
+		c.Type = strings.ToLower(c.Type)
+		if !types[c.Type] {
+			return fmt.Errorf("relation %s: unknown node type %q", c.Relation, c.Type)
+		}
+		if c.Min == nil && c.Max == nil {
+			one := 1
+			c.Min = &one
+		}
+		for i := range c.Where {
+			if err := c.Where[i].compile(); err != nil {
+				return err
+			}
+		}
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			if err := c.Any[i].compile(); err != nil {
+				return err
+			}
+		}
+	case c.Not != nil:
+		return c.Not.compile()
+	}
+	return nil
+}
+
+// kind names a condition for load errors.
+func (c Condition) kind() string {
+	switch {
+	case c.Field != "":
+		return "field " + c.Field
+	case c.Relation != "":
+		return "relation " + c.Relation
+	case len(c.Any) > 0:
+		return "any"
+	}
+	return "not"
+}
+
+// String describes a condition for violation messages.
+func (c Condition) String() string {
+	switch {
+	case c.Field != "":
+		if c.Op == "exists" || c.Op == "empty" {
+			return fmt.Sprintf("%s %s", c.Field, c.Op)
+		}
+		return fmt.Sprintf("%s %s %v", c.Field, c.Op, c.Value)
+	case c.Relation != "":
+		s := fmt.Sprintf("%s %s", c.Relation, dirOrBoth(c.Direction))
+		if c.Type != "" && c.Type != "any" {
+			s += " " + c.Type
+		}
+		if len(c.Where) > 0 {
+			var w []string
+			for _, x := range c.Where {
+				w = append(w, x.String())
+			}
+			s += " where " + strings.Join(w, " and ")
+		}
+		if c.Min != nil {
+			s += fmt.Sprintf(" min %d", *c.Min)
+		}
+		if c.Max != nil {
+			s += fmt.Sprintf(" max %d", *c.Max)
+		}
+		return s
+	case len(c.Any) > 0:
+		var w []string
+		for _, x := range c.Any {
+			w = append(w, x.String())
+		}
+		return "any(" + strings.Join(w, " | ") + ")"
+	case c.Not != nil:
+		return "not(" + c.Not.String() + ")"
+	}
+	return ""
+}
+
+func dirOrBoth(d string) string {
+	if d == "" {
+		return "both"
+	}
+	return d
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+func compile(t *testing.T, src string) (*Policy, error) {
+	t.Helper()
+	var p Policy
+	if err := json.Unmarshal([]byte(src), &p); err != nil {
+		t.Fatal(err)
+	}
+	return &p, p.Compile()
+}
+
+func TestCompileRejectsIgnoredKeys(t *testing.T) {
+	for _, tc := range []struct{ cond, err string }{
+		{`{"field": "kind", "op": "eq", "value": "binary", "where": [{"field": "name", "op": "exists"}]}`, "only apply to relation"},
+		{`{"field": "kind", "op": "exists", "min": 2}`, "only apply to relation"},
+		{`{"any": [{"field": "kind", "op": "exists"}], "type": "artifact"}`, "only apply to relation"},
+		{`{"relation": "produces", "op": "eq"}`, "only apply to field"},
+		{`{"field": "kind", "op": "exists", "relation": "produces"}`, "exactly one"},
+		{`{"relation": "produces", "where": [{"field": "kind", "op": "eq", "value": "binary"}], "min": 1}`, ""},
+	} {
+		_, err := compile(t, `{"rules": [{"id": "r", "for": "step", "require": [`+tc.cond+`]}]}`)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: %v", tc.cond, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: error %v, want %q", tc.cond, err, tc.err)
+		}
+	}
+}
+
+// The workflow example from the Policy doc passes on a signed git commit.
+func TestWorkflowExample(t *testing.T) {
+	p, err := compile(t, `{"rules": [{
+		"id": "workflow-change-signed",
+		"for": "step",
+		"where": [{"relation": "produces", "direction": "out", "type": "artifact",
+		           "where": [{"field": "name", "op": "prefix", "value": ".github/workflows/"}], "min": 1}],
+		"require": [{"relation": "produces", "direction": "out", "type": "artifact",
+		             "where": [{"field": "kind", "op": "eq", "value": "git-commit"},
+		                       {"field": "metadata.signing_key", "op": "exists"}], "min": 1}]
+	}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(n, key string) graph.AstraGraph {
+		md := map[string]string{}
+		if key != "" {
+			md["signing_key"] = key
+		}
+		return graph.AstraGraph{
+			Principals: []graph.Principal{{ID: "principal:dev"}},
+			Steps:      []graph.Step{{ID: "step:" + n}},
+			Artifacts: []graph.Artifact{
+				{ID: "commit:" + n, Kind: "git-commit", Metadata: md},
+				{ID: "file:" + n, Kind: "git-file", Name: ".github/workflows/ci.yml"},
+			},
+			Edges: []graph.Edge{
+				{Source: "principal:dev", Target: "step:" + n, Relation: "performs"},
+				{Source: "step:" + n, Target: "commit:" + n, Relation: "produces"},
+				{Source: "step:" + n, Target: "file:" + n, Relation: "produces"},
+			},
+		}
+	}
+	if res := Evaluate(p, commit("1", "AAAA")); res.Checked != 1 || len(res.Violations) != 0 {
+		t.Errorf("signed: %+v", res)
+	}
+	if res := Evaluate(p, commit("2", "")); len(res.Violations) != 1 {
+		t.Errorf("unsigned: %+v", res)
+	}
+}
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Affected is a node reached from the start node. From/Via are the last
+// hop of a shortest path, so the full chain can be followed back to the
+// start without storing a path per node.
+type Affected struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Depth int    `json:"depth"`
+	From  string `json:"from"`
+	Via   string `json:"via"`
+}
+
+type Impact struct {
+	Node      string     `json:"node"`
+	NodeType  string     `json:"node_type"`
+	MaxDepth  int        `json:"max_depth,omitempty"`
+	Steps     []Affected `json:"steps"`
+	Artifacts []Affected `json:"artifacts"`
+	Resources []Affected `json:"resources,omitempty"`
+}
+
+type hop struct {
+	to  string
+	rel string
+}
+
+/*
+ComputeImpact walks forward from node along the flow of influence
+(graph.FlowEdge) and returns every step and artifact it transitively
+affects: principal -> performed steps -> produced artifacts -> steps
+consuming them -> ...
+
+principal --uses--> resource is not followed: using a shared resource
+(resource:git) does not let a principal tamper with everybody else's
+steps. same_as is followed both ways since both IDs are the same content.
+maxDepth <= 0 means unlimited.
+*/
+func ComputeImpact(g graph.AstraGraph, node string, maxDepth int) (Impact, error) {
+	ix := graph.NewIndex(g)
+	typ, ok := ix.Types[node]
+	if !ok {
+		return Impact{}, fmt.Errorf("node not found: %s", node)
+	}
+
+	imp := Impact{Node: node, NodeType: typ, MaxDepth: maxDepth}
+	for _, a := range bfs(node, forward(g), maxDepth) {
+		a.Type = ix.Types[a.ID]
+		switch a.Type {
+		case graph.TypeStep:
+			imp.Steps = append(imp.Steps, a)
+		case graph.TypeArtifact:
+			imp.Artifacts = append(imp.Artifacts, a)
+		case graph.TypeResource:
+			imp.Resources = append(imp.Resources, a)
+		}
+	}
+	return imp, nil
+}
+
+// forward is the adjacency ComputeImpact and Downstream walk.
+func forward(g graph.AstraGraph) map[string][]hop {
+	next := map[string][]hop{}
+	for _, e := range g.Edges {
+		switch e.Relation {
+		case "uses":
+			continue
+		case "same_as":
+			next[e.Target] = append(next[e.Target], hop{e.Source, e.Relation})
+		}
+		from, to := graph.FlowEdge(e)
+		next[from] = append(next[from], hop{to, e.Relation})
+	}
+	for _, l := range next {
+		sort.Slice(l, func(i, j int) bool {
+			if l[i].to != l[j].to {
+				return l[i].to < l[j].to
+			}
+			return l[i].rel < l[j].rel
+		})
+	}
+	return next
+}
+
+// Downstream maps every node reached from any of starts, following the
+// same edges as ComputeImpact, to the sorted starts that reach it.
+func Downstream(g graph.AstraGraph, starts []string) map[string][]string {
+	next := forward(g)
+	out := map[string][]string{}
+	for _, s := range starts {
+		for _, a := range bfs(s, next, 0) {
+			out[a.ID] = append(out[a.ID], s)
+		}
+	}
+	for _, l := range out {
+		sort.Strings(l)
+	}
+	return out
+}
+
+// bfs returns every node reachable from start over next, with the last hop
+// of the first shortest path found, ordered by depth then ID.
+func bfs(start string, next map[string][]hop, maxDepth int) []Affected {
+	type visit struct {
+		prev, rel string
+		depth     int
+	}
+	seen := map[string]visit{start: {}}
+	queue := []string{start}
+	var order []string
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		d := seen[v].depth
+		if maxDepth > 0 && d >= maxDepth {
+			continue
+		}
+		for _, h := range next[v] {
+			if _, ok := seen[h.to]; ok {
+				continue
+			}
+			seen[h.to] = visit{prev: v, rel: h.rel, depth: d + 1}
+			queue = append(queue, h.to)
+			order = append(order, h.to)
+		}
+	}
+
+	out := make([]Affected, 0, len(order))
+	for _, id := range order {
+		v := seen[id]
+		out = append(out, Affected{ID: id, Depth: v.depth, From: v.prev, Via: v.rel})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Depth != out[j].Depth {
+			return out[i].Depth < out[j].Depth
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+type Trace struct {
+	Artifact string           `json:"artifact"`
+	MaxDepth int              `json:"max_depth,omitempty"`
+	Nodes    []Affected       `json:"nodes"`
+	Graph    graph.AstraGraph `json:"graph"`
+	Chain    []string         `json:"chain"`
+}
+
+/*
+ComputeTrace walks backwards from an artifact against the flow of
+influence: the step that produced it, the resources that carried that step
+out, the artifacts it consumed (source commits, files, build dependencies),
+the steps that produced those, and the principals that performed them.
+
+As in ComputeImpact, principal --uses--> resource is not followed, so a
+shared resource does not pull in every principal of the repository.
+The result holds the provenance subgraph (usable as astra graph JSON) and
+a human-readable chain, one line per node, indented by depth.
+*/
+func ComputeTrace(g graph.AstraGraph, artifact string, maxDepth int) (Trace, error) {
+	nodes, err := Upstream(g, artifact, maxDepth)
+	if err != nil {
+		return Trace{}, err
+	}
+	t := Trace{Artifact: artifact, MaxDepth: maxDepth, Nodes: nodes}
+	keep := map[string]bool{artifact: true}
+	for _, n := range nodes {
+		keep[n.ID] = true
+	}
+	t.Graph = graph.Subgraph(g, keep, func(e graph.Edge) bool { return e.Relation != "uses" })
+	t.Chain = chain(g, artifact, t.Nodes)
+	return t, nil
+}
+
+// Upstream returns every node the artifact's provenance reaches, without
+// building the subgraph or chain.
+func Upstream(g graph.AstraGraph, artifact string, maxDepth int) ([]Affected, error) {
+	ix := graph.NewIndex(g)
+	if ix.Types[artifact] != graph.TypeArtifact {
+		return nil, fmt.Errorf("artifact not found: %s", artifact)
+	}
+
+	prev := map[string][]hop{}
+	for _, e := range g.Edges {
+		switch e.Relation {
+		case "uses":
+			continue
+		case "same_as":
+			prev[e.Source] = append(prev[e.Source], hop{e.Target, e.Relation})
+		}
+		from, to := graph.FlowEdge(e)
+		prev[to] = append(prev[to], hop{from, e.Relation})
+	}
+	for _, l := range prev {
+		sort.Slice(l, func(i, j int) bool {
+			if l[i].to != l[j].to {
+				return l[i].to < l[j].to
+			}
+			return l[i].rel < l[j].rel
+		})
+	}
+
+	nodes := bfs(artifact, prev, maxDepth)
+	for i := range nodes {
+		nodes[i].Type = ix.Types[nodes[i].ID]
+	}
+	return nodes, nil
+}
+
+// chain renders the BFS tree rooted at the artifact:
+//
+//	hello_2.10-3_amd64.deb [Artifact]
+//	  <- produces build-hello@2.10-3 [Step] dpkg-buildpackage
+//	    <- carries_out hello_2.10.orig.tar.xz [Resource]
+func chain(g graph.AstraGraph, root string, nodes []Affected) []string {
+	labels := nodeLabels(g)
+	children := map[string][]Affected{}
+	for _, n := range nodes {
+		children[n.From] = append(children[n.From], n)
+	}
+
+	lines := []string{fmt.Sprintf("%s [%s]%s", root, graph.TypeArtifact, labels[root])}
+	var walk func(id string, depth int)
+	walk = func(id string, depth int) {
+		for _, c := range children[id] {
+			lines = append(lines, fmt.Sprintf("%s<- %s %s [%s]%s",
+				strings.Repeat("  ", depth), c.Via, c.ID, c.Type, labels[c.ID]))
+			walk(c.ID, depth+1)
+		}
+	}
+	walk(root, 1)
+	return lines
+}
+
+// nodeLabels returns a short " <label>" suffix per node for the chain.
+func nodeLabels(g graph.AstraGraph) map[string]string {
+	l := map[string]string{}
+	for _, n := range g.Artifacts {
+		if n.Name != "" && n.Name != n.ID {
+			l[n.ID] = " " + n.Name
+		}
+	}
+	for _, n := range g.Steps {
+		if n.Command != "" {
+			l[n.ID] = " " + n.Command
+		}
+	}
+	for _, n := range g.Principals {
+		s := n.Name
+		if n.Trust != "" {
+			s = strings.TrimSpace(s + " trust=" + n.Trust)
+		}
+		if s != "" {
+			l[n.ID] = " " + s
+		}
+	}
+	for _, n := range g.Resources {
+		if n.URI != "" {
+			l[n.ID] = " " + n.URI
+		}
+	}
+	return l
+}
@@ -0,0 +1,384 @@
+package repro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Build is one .buildinfo, flattened for comparison.
+type Build struct {
+	Label     string // where it came from, e.g. the .buildinfo path
+	Step      graph.Step
+	Origin    graph.Principal // Build-Origin, with its signing key if any
+	Source    string
+	Version   string
+	Arch      string
+	BuildPath string
+	Env       map[string]string
+	Deps      map[string]string         // package -> version
+	Outputs   map[string]graph.Artifact // file name -> artifact
+	Sources   []graph.Resource          // orig tarball and other non-dependency resources
+}
+
+// FromGraph reads a single-build graph as produced by the buildinfo parser.
+func FromGraph(label string, g *graph.AstraGraph) (Build, error) {
+	if len(g.Steps) != 1 {
+		return Build{}, fmt.Errorf("%s: expected one build step, got %d", label, len(g.Steps))
+	}
+	s := g.Steps[0]
+	b := Build{
+		Label:     label,
+		Step:      s,
+		Source:    s.Metadata["source"],
+		Version:   s.Metadata["version"],
+		Arch:      s.Arch,
+		BuildPath: s.Metadata["build_path"],
+		Env:       s.Environment,
+		Deps:      map[string]string{},
+		Outputs:   map[string]graph.Artifact{},
+	}
+	if b.Env == nil {
+		b.Env = map[string]string{}
+	}
+	if len(g.Principals) > 0 {
+		b.Origin = g.Principals[0]
+	}
+	for _, r := range g.Resources {
+		if r.Type != "build-dependency" {
+			b.Sources = append(b.Sources, r)
+			continue
+		}
+		if pkg, ver, ok := strings.Cut(r.ID, "@"); ok {
+			b.Deps[pkg] = ver
+		}
+	}
+	for _, a := range g.Artifacts {
+		b.Outputs[a.Name] = a
+	}
+	return b, nil
+}
+
+// Output status values.
+const (
+	Match   = "match"
+	Differ  = "differ"
+	OnlyA   = "only-a"
+	OnlyB   = "only-b"
+	Unknown = "unknown" // a build recorded no hash for the file
+)
+
+type OutputCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	HashA  string `json:"sha256_a,omitempty"`
+	HashB  string `json:"sha256_b,omitempty"`
+	SizeA  int64  `json:"size_a,omitempty"`
+	SizeB  int64  `json:"size_b,omitempty"`
+}
+
+// Diff is one input that differs between the builds ("" = absent).
+type Diff struct {
+	Key string `json:"key"`
+	A   string `json:"a"`
+	B   string `json:"b"`
+}
+
+type Report struct {
+	A            string        `json:"a"`
+	B            string        `json:"b"`
+	Source       string        `json:"source"`
+	Version      string        `json:"version"`
+	SameSource   bool          `json:"same_source"`
+	Reproducible bool          `json:"reproducible"`
+	Outputs      []OutputCheck `json:"outputs"`
+	Arch         *Diff         `json:"architecture,omitempty"`
+	BuildPath    *Diff         `json:"build_path,omitempty"`
+	Deps         []Diff        `json:"build_depends,omitempty"`
+	Env          []Diff        `json:"environment,omitempty"`
+	Explanations []string      `json:"explanations"`
+}
+
+// envHints are variables known to leak into build outputs.
+var envHints = map[string]string{
+	"SOURCE_DATE_EPOCH":  "timestamps embedded in outputs",
+	"TZ":                 "timezone-dependent timestamps",
+	"LANG":               "locale-dependent messages and sort order",
+	"LC_ALL":             "locale-dependent messages and sort order",
+	"LC_CTYPE":           "locale-dependent character handling",
+	"LC_COLLATE":         "locale-dependent sort order",
+	"DEB_BUILD_OPTIONS":  "build options (nocheck, parallel=, optimisation)",
+	"DEB_BUILD_PROFILES": "build profiles change what is built",
+	"CFLAGS":             "compiler flags",
+	"CXXFLAGS":           "compiler flags",
+	"LDFLAGS":            "linker flags",
+	"USER":               "user name embedded in outputs",
+	"HOME":               "home directory embedded in outputs",
+}
+
+/*
+Compare checks whether two builds of the same source and version produced
+identical .deb files, and lists the recorded input differences that could
+explain a mismatch, most likely first: Build-Path (embedded in debug info
+and __FILE__), environment variables known to leak into outputs, build
+dependency versions, other environment variables, then architecture.
+*/
+func Compare(a, b Build) Report {
+	rep := Report{
+		A:          a.Label,
+		B:          b.Label,
+		Source:     a.Source,
+		Version:    a.Version,
+		SameSource: a.Source == b.Source && a.Version == b.Version,
+	}
+
+	// matching outputs of different sources prove nothing
+	rep.Reproducible = rep.SameSource && len(a.Outputs) > 0
+	for _, name := range unionKeys(a.Outputs, b.Outputs) {
+		oa, inA := a.Outputs[name]
+		ob, inB := b.Outputs[name]
+		c := OutputCheck{Name: name, HashA: oa.Hash, HashB: ob.Hash, SizeA: oa.Size, SizeB: ob.Size}
+		switch {
+		case !inB:
+			c.Status = OnlyA
+		case !inA:
+			c.Status = OnlyB
+		case oa.Hash == "" || ob.Hash == "":
+			c.Status = Unknown
+		case strings.EqualFold(oa.Hash, ob.Hash):
+			c.Status = Match
+		default:
+			c.Status = Differ
+		}
+		if c.Status != Match {
+			rep.Reproducible = false
+		}
+		rep.Outputs = append(rep.Outputs, c)
+	}
+
+	if a.Arch != b.Arch {
+		rep.Arch = &Diff{Key: "Build-Architecture", A: a.Arch, B: b.Arch}
+	}
+	if a.BuildPath != b.BuildPath {
+		rep.BuildPath = &Diff{Key: "Build-Path", A: a.BuildPath, B: b.BuildPath}
+	}
+	rep.Deps = diffMaps(a.Deps, b.Deps)
+	rep.Env = diffMaps(a.Env, b.Env)
+
+	var ex []string
+	unknown, mismatched := 0, 0
+	for _, o := range rep.Outputs {
+		switch o.Status {
+		case Unknown:
+			unknown++
+		case Differ, OnlyA, OnlyB:
+			mismatched++
+		}
+	}
+	if unknown > 0 {
+		ex = append(ex, fmt.Sprintf("%d outputs have no recorded hash in one build; they cannot be shown to match", unknown))
+	}
+	if !rep.SameSource {
+		ex = append(ex, fmt.Sprintf("builds are of different sources: %s %s vs %s %s", a.Source, a.Version, b.Source, b.Version))
+	}
+	var leaky, otherEnv []Diff
+	for _, d := range rep.Env {
+		if _, ok := envHints[d.Key]; ok {
+			leaky = append(leaky, d)
+		} else {
+			otherEnv = append(otherEnv, d)
+		}
+	}
+	switch {
+	case rep.Reproducible:
+		if n := len(rep.Deps) + len(rep.Env); n > 0 || rep.BuildPath != nil || rep.Arch != nil {
+			ex = append(ex, "outputs match; the recorded input differences do not affect them")
+		}
+	case mismatched == 0:
+		// only unknown outputs, none at all or different sources: nothing
+		// more to explain
+	case rep.BuildPath == nil && rep.Arch == nil && len(rep.Deps) == 0 && len(rep.Env) == 0:
+		ex = append(ex, "no recorded input differs: the mismatch comes from nondeterminism in the build itself (timestamps, file ordering, randomness)")
+	default:
+		if rep.BuildPath != nil {
+			ex = append(ex, fmt.Sprintf("Build-Path differs (%s vs %s): paths embedded in debug info or __FILE__", show(rep.BuildPath.A), show(rep.BuildPath.B)))
+		}
+		for _, d := range leaky {
+			ex = append(ex, fmt.Sprintf("%s differs (%s vs %s): %s", d.Key, show(d.A), show(d.B), envHints[d.Key]))
+		}
+		if len(rep.Deps) > 0 {
+			var l []string
+			for i, d := range rep.Deps {
+				if i == 5 {
+					l = append(l, fmt.Sprintf("and %d more", len(rep.Deps)-5))
+					break
+				}
+				l = append(l, fmt.Sprintf("%s %s vs %s", d.Key, show(d.A), show(d.B)))
+			}
+			ex = append(ex, fmt.Sprintf("build dependencies differ (%d): %s", len(rep.Deps), strings.Join(l, ", ")))
+		}
+		if len(otherEnv) > 0 {
+			var keys []string
+			for _, d := range otherEnv {
+				keys = append(keys, d.Key)
+			}
+			ex = append(ex, "other environment differs: "+strings.Join(keys, ", "))
+		}
+		if rep.Arch != nil {
+			ex = append(ex, fmt.Sprintf("Build-Architecture differs (%s vs %s)", show(rep.Arch.A), show(rep.Arch.B)))
+		}
+	}
+	rep.Explanations = ex
+	return rep
+}
+
+/*
+Graph shows both builds side by side. Identical outputs and build
+dependencies are shared nodes, so the builds visibly converge on them;
+outputs that differ become one node per build, linked by differs_from.
+Build dependencies that match are only included with allDeps. Each
+build's origin is kept as it was parsed; its performs edge carries the key
+that signed that build's .buildinfo.
+*/
+func Graph(a, b Build, rep Report, allDeps bool) graph.AstraGraph {
+	var g graph.AstraGraph
+	stepID := func(x Build, side string) string { return x.Step.ID + "#" + side }
+	seenP, seenR := map[string]bool{}, map[string]bool{}
+	differ := map[string]bool{}
+	for _, d := range rep.Deps {
+		differ[d.Key] = true
+	}
+
+	for _, x := range []struct {
+		b    Build
+		side string
+	}{{a, "a"}, {b, "b"}} {
+		sid := stepID(x.b, x.side)
+		st := x.b.Step
+		st.ID = sid
+		st.Metadata = cloneMap(st.Metadata)
+		st.SetTimestamp(st.Timestamp)
+		st.Metadata["buildinfo"] = x.b.Label
+		g.Steps = append(g.Steps, st)
+
+		if o := x.b.Origin; o.ID != "" {
+			if !seenP[o.ID] {
+				seenP[o.ID] = true
+				o.Metadata = cloneMap(o.Metadata)
+				g.Principals = append(g.Principals, o)
+			}
+			// both builds may share an origin but not a signing key
+			var md map[string]string
+			if key := graph.SigningKey(o.Metadata); key != "" {
+				md = map[string]string{"pgp_key_id": key}
+			}
+			g.Edges = append(g.Edges, graph.Edge{Source: o.ID, Target: sid, Relation: "performs", Metadata: md})
+		}
+
+		for _, pkg := range sortedKeys(x.b.Deps) {
+			if !allDeps && !differ[pkg] {
+				continue
+			}
+			id := pkg + "@" + x.b.Deps[pkg]
+			if !seenR[id] {
+				seenR[id] = true
+				g.Resources = append(g.Resources, graph.Resource{ID: id, Type: "build-dependency", Format: "deb"})
+			}
+			g.Edges = append(g.Edges, graph.Edge{Source: id, Target: sid, Relation: "carries_out"})
+		}
+		for _, r := range x.b.Sources {
+			if !seenR[r.ID] {
+				seenR[r.ID] = true
+				g.Resources = append(g.Resources, r)
+			}
+			g.Edges = append(g.Edges, graph.Edge{Source: r.ID, Target: sid, Relation: "carries_out"})
+		}
+	}
+
+	for _, o := range rep.Outputs {
+		switch o.Status {
+		case Match:
+			art := a.Outputs[o.Name]
+			art.Metadata = map[string]string{"reproducible": "true"}
+			g.Artifacts = append(g.Artifacts, art)
+			g.Edges = append(g.Edges,
+				graph.Edge{Source: stepID(a, "a"), Target: o.Name, Relation: "produces"},
+				graph.Edge{Source: stepID(b, "b"), Target: o.Name, Relation: "produces"})
+		default:
+			var ids []string
+			for _, x := range []struct {
+				b    Build
+				side string
+			}{{a, "a"}, {b, "b"}} {
+				art, ok := x.b.Outputs[o.Name]
+				if !ok {
+					continue
+				}
+				art.ID = o.Name + "#" + x.side
+				repr := "false"
+				if o.Status == Unknown {
+					repr = "unknown"
+				}
+				art.Metadata = map[string]string{"reproducible": repr, "buildinfo": x.b.Label}
+				g.Artifacts = append(g.Artifacts, art)
+				g.Edges = append(g.Edges, graph.Edge{Source: stepID(x.b, x.side), Target: art.ID, Relation: "produces"})
+				ids = append(ids, art.ID)
+			}
+			if len(ids) == 2 && o.Status == Differ {
+				g.Edges = append(g.Edges, graph.Edge{Source: ids[0], Target: ids[1], Relation: "differs_from"})
+			}
+		}
+	}
+	return g
+}
+
+func diffMaps(a, b map[string]string) []Diff {
+	var out []Diff
+	for _, k := range unionKeys(a, b) {
+		if a[k] != b[k] {
+			out = append(out, Diff{Key: k, A: a[k], B: b[k]})
+		}
+	}
+	return out
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func show(s string) string {
+	if s == "" {
+		return "(unset)"
+	}
+	return s
+}
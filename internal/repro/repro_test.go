@@ -0,0 +1,69 @@
+package repro
+
+import (
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+func build(label, version, hash, key string) Build {
+	g := &graph.AstraGraph{
+		Steps: []graph.Step{{
+			ID: "build-hello@" + version, Command: "dpkg-buildpackage",
+			Metadata: map[string]string{"source": "hello", "version": version},
+		}},
+		Artifacts:  []graph.Artifact{{ID: "hello.deb", Name: "hello.deb", Hash: hash}},
+		Principals: []graph.Principal{{ID: "Debian", Trust: "unsigned"}},
+	}
+	if key != "" {
+		g.Principals[0].Trust = "signed"
+		g.Principals[0].Metadata = map[string]string{"pgp_key_id": key}
+	}
+	b, err := FromGraph(label, g)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		a, b   Build
+		status string
+		repro  bool
+	}{
+		{"match", build("a", "1-1", "ab", ""), build("b", "1-1", "AB", ""), Match, true},
+		{"differ", build("a", "1-1", "ab", ""), build("b", "1-1", "cd", ""), Differ, false},
+		{"no hash", build("a", "1-1", "ab", ""), build("b", "1-1", "", ""), Unknown, false},
+		{"other version", build("a", "1-1", "ab", ""), build("b", "1-2", "ab", ""), Match, false},
+	} {
+		rep := Compare(tc.a, tc.b)
+		if len(rep.Outputs) != 1 || rep.Outputs[0].Status != tc.status {
+			t.Errorf("%s: outputs %+v, want %s", tc.name, rep.Outputs, tc.status)
+		}
+		if rep.Reproducible != tc.repro {
+			t.Errorf("%s: reproducible %v, want %v", tc.name, rep.Reproducible, tc.repro)
+		}
+	}
+}
+
+func TestGraphKeepsOrigin(t *testing.T) {
+	a, b := build("a", "1-1", "ab", "AAAA"), build("b", "1-1", "cd", "")
+	g := Graph(a, b, Compare(a, b), false)
+	if len(g.Principals) != 1 {
+		t.Fatalf("principals %+v", g.Principals)
+	}
+	if p := g.Principals[0]; p.Trust != "signed" || p.Metadata["pgp_key_id"] != "AAAA" {
+		t.Errorf("principal %+v, want the parsed one", p)
+	}
+	keys := map[string]string{}
+	for _, e := range g.Edges {
+		if e.Relation == "performs" {
+			keys[e.Target] = e.Metadata["pgp_key_id"]
+		}
+	}
+	if keys["build-hello@1-1#a"] != "AAAA" || keys["build-hello@1-1#b"] != "" {
+		t.Errorf("performs keys %v", keys)
+	}
+}
@@ -0,0 +1,269 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+// Finding kinds.
+const (
+	FindNewcomerSensitive = "new-contributor-sensitive-change"
+	FindDormant           = "dormant-account"
+	FindKeyChange         = "signing-key-change"
+	FindUnusualHour       = "unusual-hour"
+)
+
+type AnomalyOptions struct {
+	DormantMonths   int      // gap that makes an account dormant (default 6)
+	NewcomerCommits int      // a principal's first N commits to a repository count as first-time, unless among its first N (default 3)
+	MinHistory      int      // commits needed before judging hours (default 20)
+	HourShare       float64  // unusual when hours h-1..h+1 hold less than this share of other commits (default 0.02)
+	SensitivePaths  []string // default: DefaultScoreModel().SensitivePaths
+}
+
+type Finding struct {
+	Kind      string   `json:"kind"`
+	Severity  string   `json:"severity"` // high|medium|low
+	Principal string   `json:"principal"`
+	Step      string   `json:"step"`
+	Time      string   `json:"time,omitempty"` // RFC3339, in the author's zone when known
+	Files     []string `json:"files,omitempty"`
+	Detail    string   `json:"detail"`
+
+	at time.Time
+}
+
+// commit is one step of a principal with the evidence anomalies look at.
+type commit struct {
+	step  string
+	at    time.Time
+	key   string
+	repo  string   // repository purl of the commit artifact, "" if none
+	files []string // sensitive files changed
+}
+
+/*
+DetectAnomalies looks at each principal's commit history for patterns seen
+in maintainer-takeover attacks (xz-utils):
+
+	new-contributor-sensitive-change  one of the first commits touches build/CI files,
+	                                  by a principal who joined after the repository's
+	                                  first NewcomerCommits commits (not a founder)
+	dormant-account                   first commit after a gap of DormantMonths
+	signing-key-change                signing key differs from the previous signed commit,
+	                                  or signing stops after signed commits
+	unusual-hour                      authored at an hour the principal rarely uses
+
+Commit time is the step timestamp, else the "time" of the git-commit
+artifact it produced, read in that artifact's "tz" when present so hours
+are the author's local hours. The signing key comes from the commit
+artifact ("signing_key"/"pgp_key_id") or the step metadata.
+*/
+func DetectAnomalies(g graph.AstraGraph, opts AnomalyOptions) []Finding {
+	if opts.DormantMonths <= 0 {
+		opts.DormantMonths = 6
+	}
+	if opts.NewcomerCommits <= 0 {
+		opts.NewcomerCommits = 3
+	}
+	if opts.MinHistory <= 0 {
+		opts.MinHistory = 20
+	}
+	if opts.HourShare <= 0 {
+		opts.HourShare = 0.02
+	}
+	if opts.SensitivePaths == nil {
+		opts.SensitivePaths = DefaultScoreModel().SensitivePaths
+	}
+
+	ix := graph.NewIndex(g)
+	arts := map[string]graph.Artifact{}
+	for _, a := range g.Artifacts {
+		arts[a.ID] = a
+	}
+	steps := map[string]graph.Step{}
+	for _, s := range g.Steps {
+		steps[s.ID] = s
+	}
+
+	type history struct {
+		p    graph.Principal
+		hist []commit
+	}
+	var all []history
+	for _, p := range g.Principals {
+		var hist []commit
+		for _, e := range ix.Out[p.ID] {
+			s, ok := steps[e.Target]
+			if e.Relation != "performs" || !ok {
+				continue
+			}
+			c := commit{step: s.ID}
+			if t, ok := graph.ParseTimestamp(s.Timestamp); ok {
+				c.at = t
+			}
+			c.key = graph.SigningKey(s.Metadata)
+			for _, pe := range ix.Out[s.ID] {
+				a, ok := arts[pe.Target]
+				if pe.Relation != "produces" || !ok {
+					continue
+				}
+				if a.Kind == "git-commit" {
+					if t, ok := graph.ParseTimestamp(a.Metadata["time"]); ok && c.at.IsZero() {
+						c.at = t
+					}
+					if off, ok := tzOffset(a.Metadata["tz"]); ok && !c.at.IsZero() {
+						c.at = c.at.In(time.FixedZone("", off))
+					}
+					if c.key == "" {
+						c.key = graph.SigningKey(a.Metadata)
+					}
+					if ident, ok := mapper.ParseArtifactID(a.ID); ok {
+						ident.Version = ""
+						c.repo = ident.PURL()
+					}
+				}
+				if sensitivePath(opts.SensitivePaths, a.Name) {
+					c.files = append(c.files, a.Name)
+				}
+			}
+			if c.at.IsZero() {
+				continue // nothing to order the history by
+			}
+			sort.Strings(c.files)
+			hist = append(hist, c)
+		}
+		sort.Slice(hist, func(i, j int) bool {
+			if !hist[i].at.Equal(hist[j].at) {
+				return hist[i].at.Before(hist[j].at)
+			}
+			return hist[i].step < hist[j].step
+		})
+		all = append(all, history{p, hist})
+	}
+
+	// whoever authored a repository's first commits founded it; the initial
+	// CI and build setup is theirs, not a newcomer's
+	founded := map[string]time.Time{}
+	byRepo := map[string][]time.Time{}
+	for _, h := range all {
+		for _, c := range h.hist {
+			byRepo[c.repo] = append(byRepo[c.repo], c.at)
+		}
+	}
+	for repo, ts := range byRepo {
+		sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+		founded[repo] = ts[min(opts.NewcomerCommits, len(ts))-1]
+	}
+
+	var out []Finding
+	for _, h := range all {
+		p, hist := h.p, h.hist
+		// per repository: commits so far, total, and whether the principal
+		// joined after it was founded
+		nth, total, newcomer := map[string]int{}, map[string]int{}, map[string]bool{}
+		for _, c := range hist {
+			if total[c.repo]++; total[c.repo] == 1 {
+				newcomer[c.repo] = c.at.After(founded[c.repo])
+			}
+		}
+
+		var hours [24]int
+		for _, c := range hist {
+			hours[c.at.Hour()]++
+		}
+
+		lastKey, warnedUnsigned := "", false
+		for i, c := range hist {
+			f := func(kind, sev, detail string) {
+				out = append(out, Finding{
+					Kind: kind, Severity: sev, Principal: p.ID, Step: c.step,
+					Time: c.at.Format(time.RFC3339), Files: c.files, Detail: detail, at: c.at,
+				})
+			}
+
+			n := nth[c.repo]
+			nth[c.repo]++
+			if newcomer[c.repo] && n < opts.NewcomerCommits && len(c.files) > 0 {
+				f(FindNewcomerSensitive, "high", fmt.Sprintf("commit %d of %d by this principal changes %s",
+					n+1, total[c.repo], strings.Join(c.files, ", ")))
+			}
+
+			if i > 0 {
+				gap := c.at.Sub(hist[i-1].at)
+				if gap > time.Duration(opts.DormantMonths)*30*24*time.Hour {
+					sev := "medium"
+					if len(c.files) > 0 {
+						sev = "high"
+					}
+					f(FindDormant, sev, fmt.Sprintf("first commit after %.0f days of inactivity", gap.Hours()/24))
+				}
+			}
+
+			switch {
+			case c.key != "" && lastKey != "" && !strings.EqualFold(c.key, lastKey):
+				f(FindKeyChange, "high", fmt.Sprintf("signed with %s, previously %s", c.key, lastKey))
+			case c.key == "" && lastKey != "" && !warnedUnsigned:
+				// once per switch to unsigned, not for every later commit
+				f(FindKeyChange, "medium", "unsigned after commits signed with "+lastKey)
+				warnedUnsigned = true
+			}
+			if c.key != "" {
+				lastKey, warnedUnsigned = c.key, false
+			}
+
+			if others := len(hist) - 1; others >= opts.MinHistory {
+				h := c.at.Hour()
+				near := hours[(h+23)%24] + hours[h] + hours[(h+1)%24] - 1
+				if share := float64(near) / float64(others); share < opts.HourShare {
+					f(FindUnusualHour, "low", fmt.Sprintf("authored at %02d:00, %.1f%% of %d other commits fall within an hour of it",
+						h, 100*share, others))
+				}
+			}
+		}
+	}
+
+	sev := map[string]int{"high": 0, "medium": 1, "low": 2}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Severity != out[j].Severity {
+			return sev[out[i].Severity] < sev[out[j].Severity]
+		}
+		if out[i].Principal != out[j].Principal {
+			return out[i].Principal < out[j].Principal
+		}
+		if !out[i].at.Equal(out[j].at) {
+			return out[i].at.Before(out[j].at)
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+/*
+tzOffset parses a git "+hhmm"/"-hhmm" zone into seconds east of UTC. A fixed
+zone keeps hours independent of the machine: time.Parse returns time.Local
+when the offset happens to match the host's zone, which would then apply
+the host's daylight saving rules.
+*/
+func tzOffset(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) != 5 || (s[0] != '+' && s[0] != '-') {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(s[1:3])
+	m, err2 := strconv.Atoi(s[3:5])
+	if err1 != nil || err2 != nil || m >= 60 {
+		return 0, false
+	}
+	off := h*3600 + m*60
+	if s[0] == '-' {
+		off = -off
+	}
+	return off, true
+}
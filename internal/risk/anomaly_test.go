@@ -0,0 +1,116 @@
+package risk
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// change is one commit of a git-shaped test graph.
+type change struct {
+	who   string
+	at    int64 // unix seconds
+	key   string
+	files []string
+}
+
+// gitGraph builds the graph the mapper emits for git history: principal
+// --performs--> step --produces--> commit and file artifacts.
+func gitGraph(repo string, changes ...change) graph.AstraGraph {
+	var g graph.AstraGraph
+	seen := map[string]bool{}
+	for i, c := range changes {
+		pid := "principal:" + c.who
+		if !seen[pid] {
+			seen[pid] = true
+			g.Principals = append(g.Principals, graph.Principal{ID: pid, Trust: "unknown"})
+		}
+		hash := fmt.Sprintf("%040x", i+1)
+		step := "step:commit:" + hash
+		g.Steps = append(g.Steps, graph.Step{ID: step, Command: "git commit"})
+		md := map[string]string{"time": strconv.FormatInt(c.at, 10), "tz": "+0000"}
+		if c.key != "" {
+			md["signing_key"] = c.key
+		}
+		commit := graph.Artifact{ID: "artifact:gitcommit:" + repo + "@" + hash, Kind: "git-commit", Name: hash, Metadata: md}
+		g.Artifacts = append(g.Artifacts, commit)
+		g.Edges = append(g.Edges,
+			graph.Edge{Source: pid, Target: step, Relation: "performs"},
+			graph.Edge{Source: step, Target: commit.ID, Relation: "produces"})
+		for _, f := range c.files {
+			id := "artifact:gitfile:" + repo + "@" + hash + ":" + f
+			g.Artifacts = append(g.Artifacts, graph.Artifact{ID: id, Kind: "git-file", Name: f})
+			g.Edges = append(g.Edges, graph.Edge{Source: step, Target: id, Relation: "produces"})
+		}
+	}
+	return g
+}
+
+const day = 24 * 60 * 60
+
+func kinds(fs []Finding, principal string) map[string]int {
+	out := map[string]int{}
+	for _, f := range fs {
+		if f.Principal == principal {
+			out[f.Kind]++
+		}
+	}
+	return out
+}
+
+func TestNewcomerSkipsFounders(t *testing.T) {
+	t0 := int64(1700000000)
+	g := gitGraph("github.com/a/r",
+		change{who: "founder", at: t0, files: []string{".github/workflows/ci.yml", "Makefile"}},
+		change{who: "founder", at: t0 + day, files: []string{"main.go"}},
+		change{who: "second", at: t0 + 2*day, files: []string{"configure.ac"}},
+		change{who: "founder", at: t0 + 3*day},
+		change{who: "late", at: t0 + 10*day, files: []string{"main.go"}},
+		change{who: "late", at: t0 + 11*day, files: []string{"build/ci.sh"}},
+	)
+	fs := DetectAnomalies(g, AnomalyOptions{})
+	for who, want := range map[string]int{"founder": 0, "second": 0, "late": 1} {
+		if got := kinds(fs, "principal:"+who)[FindNewcomerSensitive]; got != want {
+			t.Errorf("%s: %d newcomer findings, want %d", who, got, want)
+		}
+	}
+
+	// a second repository in the same graph has its own founders
+	other := gitGraph("github.com/b/s",
+		change{who: "late", at: t0 + 20*day, files: []string{"Dockerfile"}})
+	for i := range other.Steps {
+		other.Steps[i].ID += "-s"
+	}
+	for i := range other.Edges {
+		if other.Edges[i].Relation == "performs" {
+			other.Edges[i].Target += "-s"
+		} else {
+			other.Edges[i].Source += "-s"
+		}
+	}
+	g.Steps = append(g.Steps, other.Steps...)
+	g.Artifacts = append(g.Artifacts, other.Artifacts...)
+	g.Edges = append(g.Edges, other.Edges...)
+	if got := kinds(DetectAnomalies(g, AnomalyOptions{}), "principal:late")[FindNewcomerSensitive]; got != 1 {
+		t.Errorf("late: %d newcomer findings with a second repository, want 1", got)
+	}
+}
+
+func TestKeyChangesAndDormancy(t *testing.T) {
+	t0 := int64(1700000000)
+	g := gitGraph("github.com/a/r",
+		change{who: "m", at: t0, key: "AAAA"},
+		change{who: "m", at: t0 + day, key: "AAAA"},
+		change{who: "m", at: t0 + 2*day},
+		change{who: "m", at: t0 + 3*day},
+		change{who: "m", at: t0 + 4*day, key: "BBBB"},
+		change{who: "m", at: t0 + 400*day, key: "BBBB"},
+	)
+	got := kinds(DetectAnomalies(g, AnomalyOptions{}), "principal:m")
+	// unsigned once (not twice), then A -> B compared with the last signed commit
+	if got[FindKeyChange] != 2 || got[FindDormant] != 1 {
+		t.Errorf("findings %v, want 2 key changes and 1 dormant", got)
+	}
+}
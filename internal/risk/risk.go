@@ -1 +1,312 @@
 package risk
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// MaxPaths caps the shortest paths listed in a report; principal -> artifact
+// pairs grow with the square of the history.
+const MaxPaths = 1000
+
+type NodeCentrality struct {
+	ID          string  `json:"id"`
+	Type        string  `json:"type"`
+	InDegree    int     `json:"in_degree"`
+	OutDegree   int     `json:"out_degree"`
+	Degree      float64 `json:"degree_centrality"`
+	Betweenness float64 `json:"betweenness_centrality"`
+}
+
+type Bridge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type Path struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Length int      `json:"length"`
+	Nodes  []string `json:"nodes"`
+}
+
+type Report struct {
+	Nodes              int              `json:"nodes"`
+	Edges              int              `json:"edges"`
+	Centrality         []NodeCentrality `json:"centrality"`
+	ArticulationPoints []string         `json:"articulation_points"`
+	Bridges            []Bridge         `json:"bridges"`
+	StepOrder          []string         `json:"topological_step_order"`
+	CyclicSteps        []string         `json:"cyclic_steps,omitempty"`
+	PathsFrom          string           `json:"paths_from,omitempty"`
+	PathsTo            string           `json:"paths_to,omitempty"`
+	Paths              []Path           `json:"shortest_paths,omitempty"`
+	PathsTruncated     bool             `json:"paths_truncated,omitempty"`
+	SPOF               *SPOFReport      `json:"single_points_of_failure,omitempty"`
+	Scores             *ScoreReport     `json:"scores,omitempty"`
+	Findings           []Finding        `json:"findings,omitempty"`
+}
+
+/*
+ComputeRiskReport computes graph metrics over the flow view of g
+(see graph.FlowEdge):
+  - degree and betweenness centrality per node (directed, normalized)
+  - articulation points and bridges (undirected view): removing one
+    disconnects part of the supply chain
+  - topological order of steps; steps on a cycle are listed separately
+  - shortest paths from every node of type fromT to every node of type toT,
+    when both are given
+*/
+func ComputeRiskReport(g graph.AstraGraph, fromT, toT string) Report {
+	ix := graph.NewIndex(g)
+	r := Report{
+		Nodes:     len(ix.IDs),
+		Edges:     len(g.Edges),
+		PathsFrom: fromT,
+		PathsTo:   toT,
+	}
+
+	bc := Betweenness(ix)
+	n := float64(len(ix.IDs))
+	for _, id := range ix.IDs {
+		in, out := len(ix.FlowIn[id]), len(ix.FlowOut[id])
+		c := NodeCentrality{
+			ID:          id,
+			Type:        ix.Types[id],
+			InDegree:    in,
+			OutDegree:   out,
+			Betweenness: bc[id],
+		}
+		if n > 1 {
+			c.Degree = float64(in+out) / (n - 1)
+		}
+		r.Centrality = append(r.Centrality, c)
+	}
+	sort.SliceStable(r.Centrality, func(i, j int) bool {
+		if r.Centrality[i].Betweenness != r.Centrality[j].Betweenness {
+			return r.Centrality[i].Betweenness > r.Centrality[j].Betweenness
+		}
+		return r.Centrality[i].Degree > r.Centrality[j].Degree
+	})
+
+	r.ArticulationPoints, r.Bridges = articulation(ix)
+
+	order, cyclic := TopoOrder(ix)
+	for _, id := range order {
+		if ix.Types[id] == graph.TypeStep {
+			r.StepOrder = append(r.StepOrder, id)
+		}
+	}
+	for _, id := range cyclic {
+		if ix.Types[id] == graph.TypeStep {
+			r.CyclicSteps = append(r.CyclicSteps, id)
+		}
+	}
+
+	if fromT != "" && toT != "" {
+		r.Paths, r.PathsTruncated = shortestPaths(ix, fromT, toT, MaxPaths)
+	}
+	return r
+}
+
+// Betweenness is Brandes' algorithm on the unweighted flow graph,
+// normalized by (n-1)(n-2).
+func Betweenness(ix *graph.Index) map[string]float64 {
+	bc := make(map[string]float64, len(ix.IDs))
+	for _, s := range ix.IDs {
+		var stack []string
+		preds := map[string][]string{}
+		sigma := map[string]float64{s: 1}
+		dist := map[string]int{s: 0}
+		queue := []string{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range ix.FlowOut[v] {
+				if _, ok := dist[w]; !ok {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		delta := map[string]float64{}
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				bc[w] += delta[w]
+			}
+		}
+	}
+	if n := float64(len(ix.IDs)); n > 2 {
+		norm := (n - 1) * (n - 2)
+		for id := range bc {
+			bc[id] /= norm
+		}
+	}
+	return bc
+}
+
+// articulation finds cut vertices and bridges of the undirected view (Tarjan).
+func articulation(ix *graph.Index) ([]string, []Bridge) {
+	adj := map[string][]string{}
+	seen := map[[2]string]bool{}
+	for _, v := range ix.IDs {
+		for _, w := range ix.FlowOut[v] {
+			a, b := v, w
+			if a > b {
+				a, b = b, a
+			}
+			if seen[[2]string{a, b}] {
+				continue
+			}
+			seen[[2]string{a, b}] = true
+			adj[a] = append(adj[a], b)
+			adj[b] = append(adj[b], a)
+		}
+	}
+
+	disc := map[string]int{}
+	low := map[string]int{}
+	cut := map[string]bool{}
+	var bridges []Bridge
+	t := 0
+
+	var dfs func(v, parent string)
+	dfs = func(v, parent string) {
+		t++
+		disc[v], low[v] = t, t
+		children := 0
+		for _, w := range adj[v] {
+			if w == parent {
+				continue
+			}
+			if _, ok := disc[w]; ok {
+				low[v] = min(low[v], disc[w])
+				continue
+			}
+			children++
+			dfs(w, v)
+			low[v] = min(low[v], low[w])
+			if parent != "" && low[w] >= disc[v] {
+				cut[v] = true
+			}
+			if low[w] > disc[v] {
+				a, b := v, w
+				if a > b {
+					a, b = b, a
+				}
+				bridges = append(bridges, Bridge{Source: a, Target: b})
+			}
+		}
+		if parent == "" && children > 1 {
+			cut[v] = true
+		}
+	}
+	for _, id := range ix.IDs {
+		if _, ok := disc[id]; !ok {
+			dfs(id, "")
+		}
+	}
+
+	var points []string
+	for id := range cut {
+		points = append(points, id)
+	}
+	sort.Strings(points)
+	sort.Slice(bridges, func(i, j int) bool {
+		if bridges[i].Source != bridges[j].Source {
+			return bridges[i].Source < bridges[j].Source
+		}
+		return bridges[i].Target < bridges[j].Target
+	})
+	return points, bridges
+}
+
+// TopoOrder is Kahn's algorithm over the flow graph, ties broken by ID.
+// Nodes that are on, or downstream of, a cycle are returned in cyclic.
+func TopoOrder(ix *graph.Index) (order, cyclic []string) {
+	indeg := map[string]int{}
+	for _, id := range ix.IDs {
+		indeg[id] = len(ix.FlowIn[id])
+	}
+	ready := &idHeap{}
+	for _, id := range ix.IDs {
+		if indeg[id] == 0 {
+			heap.Push(ready, id)
+		}
+	}
+	for ready.Len() > 0 {
+		v := heap.Pop(ready).(string)
+		order = append(order, v)
+		for _, w := range ix.FlowOut[v] {
+			indeg[w]--
+			if indeg[w] == 0 {
+				heap.Push(ready, w)
+			}
+		}
+	}
+	for _, id := range ix.IDs {
+		if indeg[id] > 0 {
+			cyclic = append(cyclic, id)
+		}
+	}
+	return order, cyclic
+}
+
+// shortestPaths runs a BFS from every node of type fromT and records the
+// path to every reachable node of type toT, up to limit paths.
+func shortestPaths(ix *graph.Index, fromT, toT string, limit int) ([]Path, bool) {
+	var paths []Path
+	for _, s := range ix.IDs {
+		if !graph.MatchType(ix.Types[s], fromT) {
+			continue
+		}
+		prev := map[string]string{s: ""}
+		queue := []string{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			if v != s && graph.MatchType(ix.Types[v], toT) {
+				if len(paths) == limit {
+					return paths, true
+				}
+				var nodes []string
+				for u := v; u != ""; u = prev[u] {
+					nodes = append([]string{u}, nodes...)
+				}
+				paths = append(paths, Path{From: s, To: v, Length: len(nodes) - 1, Nodes: nodes})
+			}
+			for _, w := range ix.FlowOut[v] {
+				if _, ok := prev[w]; !ok {
+					prev[w] = v
+					queue = append(queue, w)
+				}
+			}
+		}
+	}
+	return paths, false
+}
+
+// idHeap is a min-heap of node IDs.
+type idHeap []string
+
+func (h idHeap) Len() int           { return len(h) }
+func (h idHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h idHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *idHeap) Push(x any)        { *h = append(*h, x.(string)) }
+func (h *idHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
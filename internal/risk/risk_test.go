@@ -0,0 +1,82 @@
+package risk
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// chain is principal -> step:1 -> artifact:1 -> step:2 -> artifact:2.
+func chain() graph.AstraGraph {
+	return graph.AstraGraph{
+		Principals: []graph.Principal{{ID: "principal:p"}},
+		Steps:      []graph.Step{{ID: "step:1"}, {ID: "step:2"}},
+		Artifacts:  []graph.Artifact{{ID: "artifact:1"}, {ID: "artifact:2"}},
+		Edges: []graph.Edge{
+			{Source: "principal:p", Target: "step:1", Relation: "performs"},
+			{Source: "step:1", Target: "artifact:1", Relation: "produces"},
+			{Source: "step:2", Target: "artifact:1", Relation: "consumes"},
+			{Source: "step:2", Target: "artifact:2", Relation: "produces"},
+		},
+	}
+}
+
+func TestComputeRiskReport(t *testing.T) {
+	r := ComputeRiskReport(chain(), "principal", "artifact")
+
+	if r.Nodes != 5 || r.Edges != 4 {
+		t.Errorf("nodes, edges = %d, %d, want 5, 4", r.Nodes, r.Edges)
+	}
+	if want := []string{"artifact:1", "step:1", "step:2"}; !reflect.DeepEqual(r.ArticulationPoints, want) {
+		t.Errorf("articulation points = %v, want %v", r.ArticulationPoints, want)
+	}
+	if len(r.Bridges) != 4 {
+		t.Errorf("bridges = %v, want every edge of the chain", r.Bridges)
+	}
+	if want := []string{"step:1", "step:2"}; !reflect.DeepEqual(r.StepOrder, want) || len(r.CyclicSteps) != 0 {
+		t.Errorf("step order = %v, cyclic %v, want %v", r.StepOrder, r.CyclicSteps, want)
+	}
+
+	bc := map[string]float64{}
+	for _, c := range r.Centrality {
+		bc[c.ID] = c.Betweenness
+	}
+	for id, want := range map[string]float64{"principal:p": 0, "step:1": 3.0 / 12, "artifact:1": 4.0 / 12, "step:2": 3.0 / 12, "artifact:2": 0} {
+		if math.Abs(bc[id]-want) > 1e-9 {
+			t.Errorf("betweenness(%s) = %v, want %v", id, bc[id], want)
+		}
+	}
+	if r.Centrality[0].ID != "artifact:1" {
+		t.Errorf("most central = %s, want artifact:1", r.Centrality[0].ID)
+	}
+
+	want := []Path{
+		{From: "principal:p", To: "artifact:1", Length: 2, Nodes: []string{"principal:p", "step:1", "artifact:1"}},
+		{From: "principal:p", To: "artifact:2", Length: 4, Nodes: []string{"principal:p", "step:1", "artifact:1", "step:2", "artifact:2"}},
+	}
+	if !reflect.DeepEqual(r.Paths, want) || r.PathsTruncated {
+		t.Errorf("paths = %+v (truncated %v), want %+v", r.Paths, r.PathsTruncated, want)
+	}
+	if paths, truncated := shortestPaths(graph.NewIndex(chain()), "Principal", "Artifact", 1); len(paths) != 1 || !truncated {
+		t.Errorf("limited paths = %+v, truncated %v, want 1 path and truncated", paths, truncated)
+	}
+
+	if r := ComputeRiskReport(chain(), "principal", ""); r.Paths != nil {
+		t.Errorf("paths without a target type = %+v", r.Paths)
+	}
+}
+
+func TestComputeRiskReportCycle(t *testing.T) {
+	g := chain()
+	g.Artifacts = append(g.Artifacts, graph.Artifact{ID: "artifact:3"})
+	g.Edges = append(g.Edges,
+		graph.Edge{Source: "step:2", Target: "artifact:3", Relation: "produces"},
+		graph.Edge{Source: "step:1", Target: "artifact:3", Relation: "consumes"},
+	)
+	r := ComputeRiskReport(g, "", "")
+	if want := []string{"step:1", "step:2"}; !reflect.DeepEqual(r.CyclicSteps, want) || len(r.StepOrder) != 0 {
+		t.Errorf("step order = %v, cyclic %v, want cyclic %v", r.StepOrder, r.CyclicSteps, want)
+	}
+}
@@ -0,0 +1,424 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// Features a ScoreModel can weigh. Every feature is in [0,1].
+const (
+	FeatTrust       = "trust"       // principal: risk of its trust level
+	FeatUnsigned    = "unsigned"    // principal: no signing key recorded
+	FeatTenure      = "tenure"      // principal: short history in the graph
+	FeatCentrality  = "centrality"  // any node: betweenness relative to the graph max
+	FeatSensitivity = "sensitivity" // step/artifact: touches build, CI or dependency files
+	FeatUnpinned    = "unpinned"    // step/resource: resources without a pinned URI
+	FeatEnvAnomaly  = "env_anomaly" // step: unusual or suspicious environment
+	FeatPrincipal   = "principal"   // step: highest score of its principals
+	FeatProducer    = "producer"    // artifact: highest score of its producing steps
+)
+
+// features lists what applies to each node type, in explanation order.
+var features = map[string][]string{
+	graph.TypePrincipal: {FeatTrust, FeatUnsigned, FeatTenure, FeatCentrality},
+	graph.TypeResource:  {FeatUnpinned, FeatCentrality},
+	graph.TypeStep:      {FeatPrincipal, FeatSensitivity, FeatUnpinned, FeatEnvAnomaly, FeatCentrality},
+	graph.TypeArtifact:  {FeatProducer, FeatSensitivity, FeatCentrality},
+}
+
+/*
+ScoreModel turns node features into a 0-100 risk score:
+
+	score = 100 * sum(weight_f * feature_f) / sum(weight_f)
+
+over the features that apply to the node's type. Scores flow downstream:
+a step includes its riskiest principal, an artifact its riskiest producing
+step. The model never looks at the wall clock (tenure is measured against
+the newest activity in the graph), so the same graph and model always give
+the same scores. Fields missing from a model file keep their defaults.
+*/
+type ScoreModel struct {
+	Weights        map[string]float64 `json:"weights"`
+	TrustRisk      map[string]float64 `json:"trust_risk"`      // trust level -> feature value
+	TenureDays     float64            `json:"tenure_days"`     // history after which tenure risk is 0
+	SensitivePaths []string           `json:"sensitive_paths"` // "dir/" prefixes or path.Match globs on path or base name
+	PinnedPattern  string             `json:"pinned_pattern"`  // regexp for content-pinned URIs
+	SuspiciousEnv  []string           `json:"suspicious_env"`  // env vars that are anomalous whenever set
+
+	pinned *regexp.Regexp
+}
+
+func DefaultScoreModel() ScoreModel {
+	return ScoreModel{
+		Weights: map[string]float64{
+			FeatTrust: 3, FeatUnsigned: 1, FeatTenure: 1, FeatCentrality: 1,
+			FeatSensitivity: 2, FeatUnpinned: 2, FeatEnvAnomaly: 1,
+			FeatPrincipal: 2, FeatProducer: 2,
+		},
+		TrustRisk: map[string]float64{
+			"verified": 0, "trusted": 0.2, "signed": 0.5, "new": 1, "untrusted": 1, "unknown": 0.8,
+		},
+		TenureDays: 365,
+		SensitivePaths: []string{
+			".github/workflows/", ".gitlab-ci.yml", ".circleci/", "Jenkinsfile",
+			"Makefile", "*.mk", "configure", "configure.ac", "*.m4", "CMakeLists.txt",
+			"debian/", "*.sh", "Dockerfile",
+			"go.mod", "go.sum", "package.json", "package-lock.json", "yarn.lock",
+			"setup.py", "pyproject.toml", "requirements.txt", "Cargo.toml", "Cargo.lock",
+		},
+		PinnedPattern: `(@sha256:[0-9a-f]{64}|[?&#](sha256|sha512|checksum|integrity)=|[@/][0-9a-f]{40}(/|$))`,
+		SuspiciousEnv: []string{
+			"LD_PRELOAD", "LD_LIBRARY_PATH", "LD_AUDIT", "DYLD_INSERT_LIBRARIES",
+			"http_proxy", "https_proxy", "HTTP_PROXY", "HTTPS_PROXY",
+		},
+	}
+}
+
+// LoadScoreModel reads a JSON model; missing fields keep their defaults.
+func LoadScoreModel(p string) (ScoreModel, error) {
+	m := DefaultScoreModel()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return ScoreModel{}, err
+	}
+	var in ScoreModel
+	if err := json.Unmarshal(b, &in); err != nil {
+		return ScoreModel{}, fmt.Errorf("score model %s: %w", p, err)
+	}
+	for f, w := range in.Weights {
+		if _, ok := m.Weights[f]; !ok {
+			return ScoreModel{}, fmt.Errorf("score model %s: unknown feature %q", p, f)
+		}
+		if w < 0 {
+			return ScoreModel{}, fmt.Errorf("score model %s: negative weight for %s", p, f)
+		}
+		m.Weights[f] = w
+	}
+	for t, v := range in.TrustRisk {
+		m.TrustRisk[t] = v
+	}
+	if in.TenureDays > 0 {
+		m.TenureDays = in.TenureDays
+	}
+	if in.SensitivePaths != nil {
+		m.SensitivePaths = in.SensitivePaths
+	}
+	if in.PinnedPattern != "" {
+		m.PinnedPattern = in.PinnedPattern
+	}
+	if in.SuspiciousEnv != nil {
+		m.SuspiciousEnv = in.SuspiciousEnv
+	}
+	if _, err := regexp.Compile(m.PinnedPattern); err != nil {
+		return ScoreModel{}, fmt.Errorf("score model %s: pinned_pattern: %w", p, err)
+	}
+	return m, nil
+}
+
+type ScoreTerm struct {
+	Feature      string  `json:"feature"`
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // points of the 0-100 score
+	Detail       string  `json:"detail,omitempty"`
+}
+
+type NodeScore struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	Score       float64     `json:"score"`
+	Explanation []ScoreTerm `json:"explanation"`
+}
+
+type ScoreReport struct {
+	Model     ScoreModel  `json:"model"`
+	Nodes     []NodeScore `json:"nodes"`     // principals, resources, steps
+	Artifacts []NodeScore `json:"artifacts"` // artifacts
+}
+
+// ComputeScores scores every node of g with model m.
+func ComputeScores(g graph.AstraGraph, m ScoreModel) ScoreReport {
+	m.pinned = regexp.MustCompile(m.PinnedPattern)
+	ix := graph.NewIndex(g)
+
+	bc := Betweenness(ix)
+	maxBC := 0.0
+	for _, v := range bc {
+		maxBC = math.Max(maxBC, v)
+	}
+	centrality := func(id string) (float64, string) {
+		if maxBC == 0 {
+			return 0, ""
+		}
+		return bc[id] / maxBC, fmt.Sprintf("betweenness %.4f", bc[id])
+	}
+
+	scores := map[string]float64{}
+	rep := ScoreReport{Model: m}
+
+	score := func(id, typ string, vals map[string]float64, details map[string]string) NodeScore {
+		ns := NodeScore{ID: id, Type: typ}
+		var sum, total float64
+		var raw []float64
+		for _, f := range features[typ] {
+			v, ok := vals[f]
+			if !ok {
+				continue // feature not applicable, e.g. resource without URI
+			}
+			w := m.Weights[f]
+			sum += w * v
+			total += w
+			raw = append(raw, v)
+			ns.Explanation = append(ns.Explanation, ScoreTerm{Feature: f, Value: round(v), Weight: w, Detail: details[f]})
+		}
+		if total > 0 {
+			ns.Score = round(100 * sum / total)
+			for i := range ns.Explanation {
+				c := &ns.Explanation[i]
+				c.Contribution = round(100 * c.Weight * raw[i] / total)
+			}
+		}
+		sort.SliceStable(ns.Explanation, func(i, j int) bool {
+			return ns.Explanation[i].Contribution > ns.Explanation[j].Contribution
+		})
+		scores[id] = ns.Score
+		return ns
+	}
+
+	// --- Principals ---
+	var newest time.Time
+	for _, p := range g.Principals {
+		if t, ok := graph.ParseTimestamp(p.Metadata["last_seen"]); ok && t.After(newest) {
+			newest = t
+		}
+	}
+	for _, p := range g.Principals {
+		vals, det := map[string]float64{}, map[string]string{}
+		tr, ok := m.TrustRisk[p.Trust]
+		if !ok {
+			tr = m.TrustRisk["unknown"]
+		}
+		vals[FeatTrust], det[FeatTrust] = tr, strings.TrimSpace("trust_level="+p.Trust+" "+p.TrustReason)
+		if key := graph.SigningKey(p.Metadata); key != "" {
+			vals[FeatUnsigned], det[FeatUnsigned] = 0, "signing key "+key
+		} else {
+			vals[FeatUnsigned], det[FeatUnsigned] = 1, "no signing key"
+		}
+		if first, ok := graph.ParseTimestamp(p.Metadata["first_seen"]); ok && !newest.IsZero() {
+			days := newest.Sub(first).Hours() / 24
+			vals[FeatTenure] = math.Max(0, 1-days/m.TenureDays)
+			det[FeatTenure] = fmt.Sprintf("%.0f days of history", days)
+		} else {
+			vals[FeatTenure], det[FeatTenure] = 0.5, "no activity timestamps"
+		}
+		vals[FeatCentrality], det[FeatCentrality] = centrality(p.ID)
+		rep.Nodes = append(rep.Nodes, score(p.ID, graph.TypePrincipal, vals, det))
+	}
+
+	// --- Resources ---
+	resources := map[string]graph.Resource{}
+	for _, r := range g.Resources {
+		resources[r.ID] = r
+		vals, det := map[string]float64{}, map[string]string{}
+		if r.URI != "" {
+			if m.isPinned(r) {
+				vals[FeatUnpinned], det[FeatUnpinned] = 0, "pinned: "+r.URI
+			} else {
+				vals[FeatUnpinned], det[FeatUnpinned] = 1, "unpinned: "+r.URI
+			}
+		}
+		vals[FeatCentrality], det[FeatCentrality] = centrality(r.ID)
+		rep.Nodes = append(rep.Nodes, score(r.ID, graph.TypeResource, vals, det))
+	}
+
+	// --- Steps ---
+	envDev := envDeviations(g.Steps)
+	names := map[string]string{}
+	for _, a := range g.Artifacts {
+		names[a.ID] = a.Name
+	}
+	for _, s := range g.Steps {
+		vals, det := map[string]float64{}, map[string]string{}
+
+		best, who := 0.0, ""
+		for _, e := range ix.In[s.ID] {
+			if e.Relation == "performs" {
+				if v := scores[e.Source]; who == "" || v > best {
+					best, who = v, e.Source
+				}
+			}
+		}
+		if who != "" {
+			vals[FeatPrincipal], det[FeatPrincipal] = best/100, fmt.Sprintf("%s scores %.1f", who, best)
+		}
+
+		var touched []string
+		for _, e := range ix.Out[s.ID] {
+			if e.Relation == "produces" && m.sensitive(names[e.Target]) {
+				touched = append(touched, names[e.Target])
+			}
+		}
+		if len(touched) > 0 {
+			sort.Strings(touched)
+			vals[FeatSensitivity], det[FeatSensitivity] = 1, "touches "+strings.Join(touched, ", ")
+		} else {
+			vals[FeatSensitivity] = 0
+		}
+
+		var used, unpinned []string
+		for _, e := range ix.In[s.ID] {
+			if r, ok := resources[e.Source]; ok && r.URI != "" {
+				used = append(used, r.ID)
+				if !m.isPinned(r) {
+					unpinned = append(unpinned, r.ID)
+				}
+			}
+		}
+		if len(used) > 0 {
+			vals[FeatUnpinned] = float64(len(unpinned)) / float64(len(used))
+			det[FeatUnpinned] = fmt.Sprintf("%d of %d resources unpinned", len(unpinned), len(used))
+		}
+
+		if len(s.Environment) > 0 {
+			var susp []string
+			for _, k := range m.SuspiciousEnv {
+				if _, ok := s.Environment[k]; ok {
+					susp = append(susp, k)
+				}
+			}
+			dev := envDev[s.ID]
+			switch {
+			case len(susp) > 0:
+				vals[FeatEnvAnomaly], det[FeatEnvAnomaly] = 1, "suspicious "+strings.Join(susp, ", ")
+			case len(dev) > 0:
+				vals[FeatEnvAnomaly] = float64(len(dev)) / float64(len(s.Environment))
+				det[FeatEnvAnomaly] = "unusual " + strings.Join(dev, ", ")
+			default:
+				vals[FeatEnvAnomaly] = 0
+			}
+		}
+
+		vals[FeatCentrality], det[FeatCentrality] = centrality(s.ID)
+		rep.Nodes = append(rep.Nodes, score(s.ID, graph.TypeStep, vals, det))
+	}
+
+	// --- Artifacts ---
+	for _, a := range g.Artifacts {
+		vals, det := map[string]float64{}, map[string]string{}
+		best, who := 0.0, ""
+		for _, e := range ix.In[a.ID] {
+			if e.Relation == "produces" {
+				if v := scores[e.Source]; who == "" || v > best {
+					best, who = v, e.Source
+				}
+			}
+		}
+		if who != "" {
+			vals[FeatProducer], det[FeatProducer] = best/100, fmt.Sprintf("%s scores %.1f", who, best)
+		}
+		if m.sensitive(a.Name) {
+			vals[FeatSensitivity], det[FeatSensitivity] = 1, "sensitive path "+a.Name
+		} else {
+			vals[FeatSensitivity] = 0
+		}
+		vals[FeatCentrality], det[FeatCentrality] = centrality(a.ID)
+		rep.Artifacts = append(rep.Artifacts, score(a.ID, graph.TypeArtifact, vals, det))
+	}
+
+	byScore := func(l []NodeScore) {
+		sort.SliceStable(l, func(i, j int) bool {
+			if l[i].Score != l[j].Score {
+				return l[i].Score > l[j].Score
+			}
+			return l[i].ID < l[j].ID
+		})
+	}
+	byScore(rep.Nodes)
+	byScore(rep.Artifacts)
+	return rep
+}
+
+func (m ScoreModel) sensitive(p string) bool {
+	return sensitivePath(m.SensitivePaths, p)
+}
+
+// sensitivePath matches "dir/" prefixes anywhere in p, and globs against
+// the whole path or its base name.
+func sensitivePath(patterns []string, p string) bool {
+	if p == "" {
+		return false
+	}
+	base := path.Base(p)
+	for _, pat := range patterns {
+		if strings.HasSuffix(pat, "/") {
+			if strings.HasPrefix(p, pat) || strings.Contains(p, "/"+pat) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pat, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isPinned: the URI carries a digest / commit, or the resource is a
+// versioned package (purl with version).
+func (m ScoreModel) isPinned(r graph.Resource) bool {
+	if m.pinned != nil && m.pinned.MatchString(r.URI) {
+		return true
+	}
+	return strings.HasPrefix(r.PURL, "pkg:") && strings.Contains(r.PURL, "@")
+}
+
+// envDeviations lists, per step, the env vars whose value differs from the
+// most common value of that variable across steps.
+func envDeviations(steps []graph.Step) map[string][]string {
+	counts := map[string]map[string]int{}
+	for _, s := range steps {
+		for k, v := range s.Environment {
+			if counts[k] == nil {
+				counts[k] = map[string]int{}
+			}
+			counts[k][v]++
+		}
+	}
+	modal := map[string]string{}
+	for k, vs := range counts {
+		best, n := "", -1
+		for v, c := range vs {
+			if c > n || c == n && v < best {
+				best, n = v, c
+			}
+		}
+		modal[k] = best
+	}
+	out := map[string][]string{}
+	for _, s := range steps {
+		for k, v := range s.Environment {
+			if len(counts[k]) > 1 && v != modal[k] {
+				out[s.ID] = append(out[s.ID], k)
+			}
+		}
+		sort.Strings(out[s.ID])
+	}
+	return out
+}
+
+// round keeps scores stable across platforms and runs when serialized.
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,135 @@
+package risk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+func scoreGraph() graph.AstraGraph {
+	return graph.AstraGraph{
+		Principals: []graph.Principal{
+			{ID: "old", Trust: "verified", Metadata: map[string]string{
+				"pgp_key_id": "ABCD", "first_seen": "2023-01-01T00:00:00Z", "last_seen": "2024-06-01T00:00:00Z"}},
+			{ID: "new", Trust: "new", Metadata: map[string]string{
+				"first_seen": "2024-06-01T00:00:00Z", "last_seen": "2024-06-01T00:00:00Z"}},
+		},
+		Resources: []graph.Resource{
+			{ID: "pinned", URI: "https://example.org/src.tar.gz?sha256=00"},
+			{ID: "floating", URI: "https://example.org/latest.tar.gz"},
+		},
+		Steps: []graph.Step{
+			{ID: "step:docs", Environment: map[string]string{"LANG": "C"}},
+			{ID: "step:ci", Environment: map[string]string{"LANG": "C", "LD_PRELOAD": "/tmp/x.so"}},
+		},
+		Artifacts: []graph.Artifact{
+			{ID: "readme", Name: "README.md"},
+			{ID: "workflow", Name: ".github/workflows/ci.yml"},
+		},
+		Edges: []graph.Edge{
+			{Source: "old", Target: "step:docs", Relation: "performs"},
+			{Source: "new", Target: "step:ci", Relation: "performs"},
+			{Source: "pinned", Target: "step:docs", Relation: "carries_out"},
+			{Source: "floating", Target: "step:ci", Relation: "carries_out"},
+			{Source: "step:docs", Target: "readme", Relation: "produces"},
+			{Source: "step:ci", Target: "workflow", Relation: "produces"},
+		},
+	}
+}
+
+func TestComputeScores(t *testing.T) {
+	rep := ComputeScores(scoreGraph(), DefaultScoreModel())
+
+	scores := map[string]NodeScore{}
+	for _, n := range append(rep.Nodes, rep.Artifacts...) {
+		scores[n.ID] = n
+	}
+	for _, pair := range [][2]string{{"new", "old"}, {"floating", "pinned"}, {"step:ci", "step:docs"}, {"workflow", "readme"}} {
+		if hi, lo := scores[pair[0]].Score, scores[pair[1]].Score; hi <= lo {
+			t.Errorf("%s scores %v, want more than %s (%v)", pair[0], hi, pair[1], lo)
+		}
+	}
+	if s := scores["old"].Score; s != 0 {
+		t.Errorf("verified, signed, long-standing principal scores %v, want 0", s)
+	}
+	// the step inherits its principal's risk and adds its own
+	if rep.Nodes[0].ID != "step:ci" {
+		t.Errorf("riskiest node = %s, want step:ci", rep.Nodes[0].ID)
+	}
+
+	values := func(id string) map[string]float64 {
+		out := map[string]float64{}
+		for _, term := range scores[id].Explanation {
+			out[term.Feature] = term.Value
+		}
+		return out
+	}
+	if v := values("step:ci"); v[FeatSensitivity] != 1 || v[FeatUnpinned] != 1 || v[FeatEnvAnomaly] != 1 {
+		t.Errorf("step:ci features = %v", v)
+	}
+	if v := values("new"); v[FeatUnsigned] != 1 || v[FeatTenure] != 1 || v[FeatTrust] != 1 {
+		t.Errorf("new features = %v", v)
+	}
+	if v := values("workflow"); v[FeatProducer] != round(scores["step:ci"].Score/100) {
+		t.Errorf("workflow producer = %v, want step:ci's score", v[FeatProducer])
+	}
+
+	if again := ComputeScores(scoreGraph(), DefaultScoreModel()); !reflect.DeepEqual(again, rep) {
+		t.Error("scores differ between runs")
+	}
+}
+
+func TestLoadScoreModel(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	m, err := LoadScoreModel(write("partial.json", `{"weights": {"trust": 5}, "trust_risk": {"signed": 0.1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := DefaultScoreModel()
+	if m.Weights[FeatTrust] != 5 || m.Weights[FeatUnsigned] != def.Weights[FeatUnsigned] {
+		t.Errorf("weights = %v", m.Weights)
+	}
+	if m.TrustRisk["signed"] != 0.1 || m.TrustRisk["new"] != 1 || m.TenureDays != def.TenureDays {
+		t.Errorf("model = %+v, want defaults kept", m)
+	}
+
+	for name, body := range map[string]string{
+		"feature.json":  `{"weights": {"popularity": 1}}`,
+		"negative.json": `{"weights": {"trust": -1}}`,
+		"pattern.json":  `{"pinned_pattern": "("}`,
+	} {
+		if _, err := LoadScoreModel(write(name, body)); err == nil {
+			t.Errorf("LoadScoreModel(%s) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestSensitivePath(t *testing.T) {
+	pats := DefaultScoreModel().SensitivePaths
+	for p, want := range map[string]bool{
+		".github/workflows/ci.yml":     true,
+		"sub/.github/workflows/ci.yml": true,
+		"Makefile":                     true,
+		"vendor/x/Makefile":            true,
+		"build/release.sh":             true,
+		"debian/rules":                 true,
+		"src/main.c":                   false,
+		"docs/github/workflows.md":     false,
+		"":                             false,
+	} {
+		if got := sensitivePath(pats, p); got != want {
+			t.Errorf("sensitivePath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
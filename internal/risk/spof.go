@@ -0,0 +1,411 @@
+package risk
+
+import (
+	"math/bits"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+type SPOFOptions struct {
+	// AllArtifacts analyzes every artifact; by default only releases, i.e.
+	// artifacts no step consumes (head commit, current file versions, .debs).
+	AllArtifacts bool
+	// DirDepth is how many leading path segments form a bus-factor group
+	// for git files (1: "cmd", 2: "cmd/astra").
+	DirDepth int
+}
+
+type ArtifactExposure struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Principals is everyone upstream of the artifact.
+	Principals []string `json:"principals"`
+	// SinglePoints can alter the artifact on their own: they are the only
+	// principal of some upstream step.
+	SinglePoints []string `json:"single_points"`
+	// CheapestStep is the smallest performer set of a single upstream
+	// step: compromising all of them alters the artifact, and under the
+	// model of ComputeSPOF no smaller set does.
+	CheapestStep []string `json:"cheapest_step"`
+}
+
+type PrincipalRisk struct {
+	Rank  int    `json:"rank"`
+	ID    string `json:"id"`
+	Trust string `json:"trust_level"`
+	// Artifacts the principal is upstream of.
+	Reach int `json:"reach"`
+	// Artifacts the principal can alter alone.
+	Alone int `json:"alone"`
+	// Artifacts whose only upstream principal this is.
+	Sole int `json:"sole"`
+}
+
+type Contribution struct {
+	Principal string `json:"principal"`
+	Changes   int    `json:"changes"`
+}
+
+type BusFactor struct {
+	Group        string         `json:"group"`
+	Kind         string         `json:"kind"` // directory|package
+	Versions     int            `json:"versions"`
+	BusFactor    int            `json:"bus_factor"`
+	Contributors []Contribution `json:"contributors"`
+}
+
+type SPOFReport struct {
+	Artifacts  []ArtifactExposure `json:"artifacts"`
+	Principals []PrincipalRisk    `json:"principals"`
+	BusFactors []BusFactor        `json:"bus_factors"`
+}
+
+/*
+ComputeSPOF finds the principals whose compromise could alter each release.
+
+Control flows along graph.FlowEdge except principal --uses--> resource:
+a shared resource (resource:git) does not give one committer control over
+everybody else's steps, so resources pass control on from their own
+upstream (resource --carries_out--> step) but are never part of the cut;
+principals reach steps through performs edges. A step is subverted only
+if all principals that perform it are compromised (author + reviewer,
+builder + signer); an artifact is altered if any upstream step is
+subverted. Under that model the minimum cut is exact and cheap: a set
+alters the artifact iff it covers the performers of some upstream step,
+so the smallest one is the performer set of the cheapest upstream step.
+A principal that is alone on an upstream step is a single point of
+failure.
+
+Principals are ranked by how many releases they control alone.
+*/
+func ComputeSPOF(g graph.AstraGraph, opts SPOFOptions) SPOFReport {
+	if opts.DirDepth <= 0 {
+		opts.DirDepth = 1
+	}
+	ix := graph.NewIndex(g)
+
+	var princs []string
+	pIdx := map[string]int{}
+	for _, p := range g.Principals {
+		pIdx[p.ID] = len(princs)
+		princs = append(princs, p.ID)
+	}
+	words := (len(princs) + 63) / 64
+
+	// control adjacency and per-step principals
+	out := map[string][]string{}
+	in := map[string][]string{}
+	performers := map[string]bitset{}
+	for _, e := range g.Edges {
+		if e.Relation == "uses" {
+			continue
+		}
+		from, to := graph.FlowEdge(e)
+		if from == to {
+			continue
+		}
+		out[from] = append(out[from], to)
+		in[to] = append(in[to], from)
+		if i, ok := pIdx[from]; ok && ix.Types[to] == graph.TypeStep {
+			b, ok := performers[to]
+			if !ok {
+				b = newBitset(words)
+				performers[to] = b
+			}
+			b.set(i)
+		}
+	}
+
+	anyUp := map[string]bitset{}
+	alone := map[string]bitset{}
+	cheapest := map[string]bitset{}
+	visit := func(v string) bool {
+		a, al, ch := newBitset(words), newBitset(words), bitset(nil)
+		for _, u := range in[v] {
+			a.or(anyUp[u])
+			al.or(alone[u])
+			ch = cheaper(ch, cheapest[u])
+		}
+		if p, ok := performers[v]; ok {
+			a.or(p)
+			if p.count() == 1 {
+				al.or(p)
+			}
+			ch = cheaper(ch, p)
+		}
+		changed := !a.equal(anyUp[v]) || !al.equal(alone[v]) || !ch.equal(cheapest[v])
+		anyUp[v], alone[v], cheapest[v] = a, al, ch
+		return changed
+	}
+
+	order, cyclic := controlOrder(ix.IDs, in, out)
+	for _, v := range order {
+		visit(v)
+	}
+	// nodes on cycles: iterate to a fixpoint (sets only grow / shrink monotonically)
+	for changed := len(cyclic) > 0; changed; {
+		changed = false
+		for _, v := range cyclic {
+			if visit(v) {
+				changed = true
+			}
+		}
+	}
+
+	var rep SPOFReport
+	stats := make([]PrincipalRisk, len(princs))
+	for i, p := range g.Principals {
+		stats[i] = PrincipalRisk{ID: p.ID, Trust: p.Trust}
+	}
+	for _, a := range g.Artifacts {
+		if !opts.AllArtifacts && consumed(a.ID, out, ix) {
+			continue
+		}
+		e := ArtifactExposure{
+			ID:           a.ID,
+			Kind:         a.Kind,
+			Principals:   anyUp[a.ID].members(princs),
+			SinglePoints: alone[a.ID].members(princs),
+			CheapestStep: cheapest[a.ID].members(princs),
+		}
+		rep.Artifacts = append(rep.Artifacts, e)
+		for _, i := range anyUp[a.ID].indices() {
+			stats[i].Reach++
+		}
+		for _, i := range alone[a.ID].indices() {
+			stats[i].Alone++
+		}
+		if anyUp[a.ID].count() == 1 {
+			stats[anyUp[a.ID].indices()[0]].Sole++
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Sole != b.Sole {
+			return a.Sole > b.Sole
+		}
+		if a.Alone != b.Alone {
+			return a.Alone > b.Alone
+		}
+		if a.Reach != b.Reach {
+			return a.Reach > b.Reach
+		}
+		return a.ID < b.ID
+	})
+	for i := range stats {
+		stats[i].Rank = i + 1
+	}
+	rep.Principals = stats
+	rep.BusFactors = busFactors(g, ix, performers, princs, opts.DirDepth)
+	return rep
+}
+
+// consumed reports whether any step takes the artifact as input.
+func consumed(id string, out map[string][]string, ix *graph.Index) bool {
+	for _, w := range out[id] {
+		if ix.Types[w] == graph.TypeStep {
+			return true
+		}
+	}
+	return false
+}
+
+// controlOrder is a topological order of the control graph; nodes on
+// cycles are returned separately.
+func controlOrder(ids []string, in, out map[string][]string) (order, cyclic []string) {
+	indeg := map[string]int{}
+	for _, id := range ids {
+		indeg[id] = len(in[id])
+	}
+	var ready []string
+	for _, id := range ids {
+		if indeg[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		v := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, v)
+		for _, w := range out[v] {
+			indeg[w]--
+			if indeg[w] == 0 {
+				ready = append(ready, w)
+			}
+		}
+	}
+	for _, id := range ids {
+		if indeg[id] > 0 {
+			cyclic = append(cyclic, id)
+		}
+	}
+	return order, cyclic
+}
+
+/*
+busFactors groups produced artifacts (git files by directory prefix,
+everything else by package URL without version) and computes the truck
+factor: the fewest principals that together made more than half of the
+changes in the group.
+*/
+func busFactors(g graph.AstraGraph, ix *graph.Index, performers map[string]bitset, princs []string, depth int) []BusFactor {
+	type group struct {
+		kind     string
+		versions int
+		changes  map[string]int
+	}
+	groups := map[string]*group{}
+
+	for _, a := range g.Artifacts {
+		key, kind := "", ""
+		if a.Kind == "git-file" {
+			key, kind = graph.DirPrefix(a.Name, depth), "directory"
+		} else if a.PURL != "" && !strings.HasPrefix(a.Kind, "git-") {
+			if ident, ok := mapper.ParsePURL(a.PURL); ok {
+				ident.Version, ident.Qualifiers, ident.Subpath = "", nil, ""
+				key, kind = ident.PURL(), "package"
+			}
+		}
+		if key == "" {
+			continue
+		}
+
+		var who []string
+		for _, e := range ix.In[a.ID] {
+			if e.Relation == "produces" {
+				who = append(who, performers[e.Source].members(princs)...)
+			}
+		}
+		if len(who) == 0 {
+			continue
+		}
+		gr, ok := groups[key]
+		if !ok {
+			gr = &group{kind: kind, changes: map[string]int{}}
+			groups[key] = gr
+		}
+		gr.versions++
+		for _, p := range who {
+			gr.changes[p]++
+		}
+	}
+
+	var out []BusFactor
+	for key, gr := range groups {
+		bf := BusFactor{Group: key, Kind: gr.kind, Versions: gr.versions}
+		total := 0
+		for p, n := range gr.changes {
+			bf.Contributors = append(bf.Contributors, Contribution{Principal: p, Changes: n})
+			total += n
+		}
+		sort.Slice(bf.Contributors, func(i, j int) bool {
+			if bf.Contributors[i].Changes != bf.Contributors[j].Changes {
+				return bf.Contributors[i].Changes > bf.Contributors[j].Changes
+			}
+			return bf.Contributors[i].Principal < bf.Contributors[j].Principal
+		})
+		covered := 0
+		for _, c := range bf.Contributors {
+			bf.BusFactor++
+			covered += c.Changes
+			if covered*2 > total {
+				break
+			}
+		}
+		out = append(out, bf)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].BusFactor != out[j].BusFactor {
+			return out[i].BusFactor < out[j].BusFactor
+		}
+		if out[i].Versions != out[j].Versions {
+			return out[i].Versions > out[j].Versions
+		}
+		return out[i].Group < out[j].Group
+	})
+	return out
+}
+
+// bitset is a fixed-size set of principal indices.
+type bitset []uint64
+
+func newBitset(words int) bitset { return make(bitset, words) }
+
+func (b bitset) set(i int) { b[i/64] |= 1 << (uint(i) % 64) }
+
+func (b bitset) or(o bitset) {
+	for i := range o {
+		b[i] |= o[i]
+	}
+}
+
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+func (b bitset) equal(o bitset) bool {
+	if (b == nil) != (o == nil) || len(b) != len(o) {
+		return false
+	}
+	for i := range b {
+		if b[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) indices() []int {
+	var out []int
+	for w, word := range b {
+		for word != 0 {
+			t := bits.TrailingZeros64(word)
+			out = append(out, w*64+t)
+			word &^= 1 << uint(t)
+		}
+	}
+	return out
+}
+
+func (b bitset) members(names []string) []string {
+	var out []string
+	for _, i := range b.indices() {
+		out = append(out, names[i])
+	}
+	sort.Strings(out)
+	return out
+}
+
+// cheaper returns the smaller non-empty set, ties broken deterministically
+// by the lowest differing member.
+func cheaper(a, b bitset) bitset {
+	if b == nil || b.count() == 0 {
+		return a
+	}
+	if a == nil || a.count() == 0 {
+		return b
+	}
+	if ca, cb := a.count(), b.count(); ca != cb {
+		if ca < cb {
+			return a
+		}
+		return b
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			if bits.TrailingZeros64(a[i]^b[i]) == bits.TrailingZeros64(a[i]&(a[i]^b[i])) {
+				return a
+			}
+			return b
+		}
+	}
+	return a
+}
@@ -0,0 +1,99 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/graph"
+)
+
+// reviewedBuild: carol commits the source alone, alice and bob both
+// perform the build, dave only uses the shared git resource.
+func reviewedBuild() graph.AstraGraph {
+	return graph.AstraGraph{
+		Principals: []graph.Principal{{ID: "alice"}, {ID: "bob"}, {ID: "carol"}, {ID: "dave"}},
+		Resources:  []graph.Resource{{ID: "resource:git"}},
+		Steps:      []graph.Step{{ID: "step:commit"}, {ID: "step:build"}},
+		Artifacts:  []graph.Artifact{{ID: "source"}, {ID: "release"}},
+		Edges: []graph.Edge{
+			{Source: "carol", Target: "step:commit", Relation: "performs"},
+			{Source: "step:commit", Target: "source", Relation: "produces"},
+			{Source: "alice", Target: "step:build", Relation: "performs"},
+			{Source: "bob", Target: "step:build", Relation: "performs"},
+			{Source: "step:build", Target: "source", Relation: "consumes"},
+			{Source: "step:build", Target: "release", Relation: "produces"},
+			{Source: "dave", Target: "resource:git", Relation: "uses"},
+			{Source: "resource:git", Target: "step:build", Relation: "carries_out"},
+		},
+	}
+}
+
+func TestComputeSPOF(t *testing.T) {
+	rep := ComputeSPOF(reviewedBuild(), SPOFOptions{})
+	if len(rep.Artifacts) != 1 {
+		t.Fatalf("artifacts = %+v, want only the release", rep.Artifacts)
+	}
+	want := ArtifactExposure{
+		ID:           "release",
+		Principals:   []string{"alice", "bob", "carol"},
+		SinglePoints: []string{"carol"},
+		CheapestStep: []string{"carol"},
+	}
+	if !reflect.DeepEqual(rep.Artifacts[0], want) {
+		t.Errorf("release = %+v, want %+v", rep.Artifacts[0], want)
+	}
+	if p := rep.Principals[0]; p.ID != "carol" || p.Rank != 1 || p.Alone != 1 || p.Reach != 1 {
+		t.Errorf("top principal = %+v, want carol controlling the release alone", p)
+	}
+	for _, p := range rep.Principals {
+		if p.ID == "dave" && p.Reach != 0 {
+			t.Errorf("dave reaches %d artifacts through a shared resource", p.Reach)
+		}
+	}
+
+	if rep := ComputeSPOF(reviewedBuild(), SPOFOptions{AllArtifacts: true}); len(rep.Artifacts) != 2 {
+		t.Errorf("AllArtifacts: %d artifacts, want 2", len(rep.Artifacts))
+	}
+}
+
+func TestComputeSPOFCheapestStep(t *testing.T) {
+	g := reviewedBuild()
+	// a second reviewer on the commit: no principal is alone upstream,
+	// and the smallest cut is either two-person step.
+	g.Principals = append(g.Principals, graph.Principal{ID: "erin"})
+	g.Edges = append(g.Edges, graph.Edge{Source: "erin", Target: "step:commit", Relation: "performs"})
+
+	rep := ComputeSPOF(g, SPOFOptions{})
+	a := rep.Artifacts[0]
+	if len(a.SinglePoints) != 0 {
+		t.Errorf("single points = %v, want none", a.SinglePoints)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(a.CheapestStep, want) {
+		t.Errorf("cheapest step = %v, want %v", a.CheapestStep, want)
+	}
+	for _, p := range rep.Principals {
+		if p.Sole != 0 || p.Alone != 0 {
+			t.Errorf("%s: sole %d, alone %d, want 0", p.ID, p.Sole, p.Alone)
+		}
+	}
+}
+
+func TestBusFactors(t *testing.T) {
+	t0 := int64(1700000000)
+	g := gitGraph("github.com/a/r",
+		change{who: "a", at: t0, files: []string{"cmd/main.go", "internal/x.go"}},
+		change{who: "a", at: t0 + day, files: []string{"cmd/main.go"}},
+		change{who: "a", at: t0 + 2*day, files: []string{"cmd/flags.go"}},
+		change{who: "b", at: t0 + 3*day, files: []string{"cmd/main.go", "internal/x.go"}},
+	)
+	got := map[string]BusFactor{}
+	for _, bf := range ComputeSPOF(g, SPOFOptions{}).BusFactors {
+		got[bf.Group] = bf
+	}
+	if bf := got["cmd"]; bf.BusFactor != 1 || bf.Versions != 4 || bf.Kind != "directory" {
+		t.Errorf("cmd = %+v, want bus factor 1 over 4 versions", bf)
+	}
+	if bf := got["internal"]; bf.BusFactor != 2 || bf.Versions != 2 {
+		t.Errorf("internal = %+v, want bus factor 2 over 2 versions", bf)
+	}
+}
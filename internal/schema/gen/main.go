@@ -0,0 +1,25 @@
+// Command gen writes the published JSON Schema documents. go generate runs
+// it instead of astra schema, so regenerating them only needs this package
+// to build.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abuishgair/astra/internal/schema"
+)
+
+func main() {
+	out := flag.String("o", "schema", "output directory for the JSON Schema documents")
+	flag.Parse()
+	paths, err := schema.Write(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	for _, p := range paths {
+		fmt.Println("[OK] Schema ->", p)
+	}
+}
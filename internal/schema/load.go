@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	parse "github.com/abuishgair/astra/internal/parser"
+)
+
+// maxErrors caps the validation problems listed in one error.
+const maxErrors = 10
+
+/*
+LoadGraph reads an AStRA graph file, upgrades it in memory when it was
+written by an older astra and validates it against Graph(). Warnings say
+when a file was upgraded, so it can be rewritten with astra migrate.
+*/
+func LoadGraph(path string) (graph.AstraGraph, []string, error) {
+	var g graph.AstraGraph
+	ws, err := load(path, KindGraph, &g)
+	return g, ws, err
+}
+
+// LoadMapped is LoadGraph for parser output (parser.Mapped).
+func LoadMapped(path string) (parse.Mapped, []string, error) {
+	var m parse.Mapped
+	ws, err := load(path, KindMapped, &m)
+	return m, ws, err
+}
+
+func load(path, kind string, v any) ([]string, error) {
+	doc, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if got, err := Detect(doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	} else if got != kind {
+		return nil, fmt.Errorf("%s: is a %s file, expected %s", path, got, kind)
+	}
+	from, notes, err := Upgrade(kind, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var ws []string
+	if cur := Current(kind); from < cur {
+		ws = append(ws, fmt.Sprintf("%s: schema version %d upgraded in memory to %d; rewrite it with astra migrate -i %s", path, from, cur, path))
+		for _, n := range notes {
+			ws = append(ws, path+": "+n)
+		}
+	}
+	if err := check(path, kind, doc); err != nil {
+		return ws, err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return ws, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ws, fmt.Errorf("%s: %w", path, err)
+	}
+	return ws, nil
+}
+
+/*
+Migrate upgrades the graph or parsed-records file in to the current schema
+version and writes it to out (which may be in). The document is rewritten
+as generic JSON, so fields this build does not know survive. from and to
+are the versions before and after; to == from means nothing changed and
+nothing was written.
+*/
+func Migrate(in, out string) (kind string, from, to int, notes []string, err error) {
+	doc, err := read(in)
+	if err != nil {
+		return "", 0, 0, nil, err
+	}
+	if kind, err = Detect(doc); err != nil {
+		return "", 0, 0, nil, fmt.Errorf("%s: %w", in, err)
+	}
+	if from, notes, err = Upgrade(kind, doc); err != nil {
+		return kind, from, from, nil, fmt.Errorf("%s: %w", in, err)
+	}
+	if err := check(in, kind, doc); err != nil {
+		return kind, from, from, notes, err
+	}
+	to = Current(kind)
+	if from == to && in == out {
+		return kind, from, to, notes, nil
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return kind, from, to, notes, err
+	}
+	return kind, from, to, notes, os.WriteFile(out, b, 0o644)
+}
+
+func read(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a JSON object, got %s", path, jsonType(v))
+	}
+	return doc, nil
+}
+
+func check(path, kind string, doc map[string]any) error {
+	s, err := For(kind)
+	if err != nil {
+		return err
+	}
+	errs := Validate(s, doc)
+	if len(errs) == 0 {
+		return nil
+	}
+	n, more := len(errs), ""
+	if n > maxErrors {
+		more = fmt.Sprintf("\n  ... and %d more", n-maxErrors)
+		errs = errs[:maxErrors]
+	}
+	return fmt.Errorf("%s does not match %s: %d problems\n  %s%s", path, s["$id"], n, strings.Join(errs, "\n  "), more)
+}
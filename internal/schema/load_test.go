@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/condense"
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/importer"
+	"github.com/abuishgair/astra/internal/mapper"
+	parse "github.com/abuishgair/astra/internal/parser"
+	"github.com/abuishgair/astra/internal/repro"
+)
+
+// Every graph astra writes is stamped with the current schema version, so
+// each producer's output must load back through LoadGraph unchanged.
+func TestProducersRoundTrip(t *testing.T) {
+	mapped := parse.Mapped{Source: "git", Mapped: []parse.Record{
+		{
+			Step:         parse.Item{ID: "step:commit:1", Attrs: map[string]string{"time": "1700000000"}},
+			Principal:    parse.Item{ID: "principal:a@example.com"},
+			ArtifactsOut: []parse.Item{{ID: "artifact:gitcommit:1", Kind: "git-commit"}},
+		},
+		{
+			Step:        parse.Item{ID: "step:commit:2", Attrs: map[string]string{"time": "yesterday"}},
+			ArtifactsIn: []parse.Item{{ID: "artifact:gitcommit:1", Kind: "git-commit"}},
+		},
+	}}
+
+	guac, _, err := importer.GUAC([]byte(`{"data":{"neighbors":[{"__typename":"HasSLSA","id":"s1",
+		"subject":{"algorithm":"sha256","digest":"ab"},
+		"slsa":{"builtFrom":[],"builtBy":{"uri":"https://builder"},"buildType":"t","startedOn":"not a time"}}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spdx, _, err := importer.SPDX([]byte(`{"spdxVersion":"SPDX-2.3","documentNamespace":"https://example.com/doc",
+		"creationInfo":{"created":"2024-01-01T12:00:00+02:00","creators":["Tool: t"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the shape of buildinfo.ParseGraph output before its step time is
+	// normalized: Build-Date is RFC 2822
+	buildinfo := func(date string) repro.Build {
+		b, err := repro.FromGraph(date, &graph.AstraGraph{Steps: []graph.Step{{
+			ID: "build-hello@1.0-1", Command: "dpkg-buildpackage", Timestamp: date,
+			Metadata: map[string]string{"source": "hello", "version": "1.0-1"},
+		}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	a, b := buildinfo("Mon, 01 Jan 2024 12:00:00 +0000"), buildinfo("garbage")
+	rebuilt := repro.Graph(a, b, repro.Compare(a, b), false)
+
+	mg := mapper.ToAstraGraph(mapped)
+	dag, _ := condense.Acyclic(mg)
+
+	dir := t.TempDir()
+	for name, g := range map[string]graph.AstraGraph{
+		"mapper": mg, "guac": guac, "spdx": spdx, "repro": rebuilt, "acyclic": dag,
+	} {
+		p := filepath.Join(dir, name+".json")
+		raw, err := json.Marshal(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		back, ws, err := LoadGraph(p)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(ws) > 0 {
+			t.Errorf("%s: unexpected warnings %v", name, ws)
+		}
+		if back.SchemaVersion != graph.SchemaVersion {
+			t.Errorf("%s: schema_version %d, want %d", name, back.SchemaVersion, graph.SchemaVersion)
+		}
+	}
+
+	// unparseable times are kept in metadata, not dropped
+	for _, s := range append(mg.Steps, rebuilt.Steps...) {
+		if s.Timestamp == "" && s.Metadata["timestamp"] == "" {
+			t.Errorf("step %s lost its raw time", s.ID)
+		}
+	}
+	if guac.Steps[0].Timestamp != "" || guac.Steps[0].Metadata["timestamp"] != "not a time" {
+		t.Errorf("guac startedOn: timestamp %q, metadata %q", guac.Steps[0].Timestamp, guac.Steps[0].Metadata["timestamp"])
+	}
+}
+
+func TestLoadGraphMigratesUnversioned(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "old.json")
+	old := `{"artifacts":null,"principals":null,"resources":null,"edges":null,"steps":[
+		{"id":"s","command":"c","timestamp":"1700000000","architecture":"","environment":null},
+		{"id":"t","command":"c","timestamp":"2024-01-01T12:00:00.123456789+02:00","architecture":"","environment":null}]}`
+	if err := os.WriteFile(p, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, ws, err := LoadGraph(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) == 0 {
+		t.Error("no warning for an upgraded file")
+	}
+	for i, want := range []string{"2023-11-14T22:13:20Z", "2024-01-01T10:00:00.123456789Z"} {
+		if got := g.Steps[i].Timestamp; got != want {
+			t.Errorf("timestamp %q, want %q", got, want)
+		}
+	}
+}
+
+// The checked-in schema/*.json must match the types; go generate
+// ./internal/schema rewrites them.
+func TestPublishedSchemasUpToDate(t *testing.T) {
+	paths, err := Write(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		want, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "..", "schema", filepath.Base(p)))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("schema/%s is stale (%v); run go generate ./internal/schema", filepath.Base(p), err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abuishgair/astra/internal/graph"
+	parse "github.com/abuishgair/astra/internal/parser"
+)
+
+/*
+A migration upgrades a decoded document from version from to from+1 and
+returns notes on what it changed. Migrations work on the generic JSON
+value, not the Go types, so they keep working after the types move on:
+never edit a migration once released, add the next one.
+*/
+type migration struct {
+	from  int
+	apply func(doc map[string]any) []string
+}
+
+var migrations = map[string][]migration{
+	KindGraph:  {{0, graphV1}},
+	KindMapped: {{0, func(map[string]any) []string { return nil }}},
+}
+
+// Current returns the schema version this build writes for kind.
+func Current(kind string) int {
+	if kind == KindMapped {
+		return parse.SchemaVersion
+	}
+	return graph.SchemaVersion
+}
+
+// ErrUnversioned is returned by Detect for JSON that is neither a graph nor
+// parsed records, such as reports and condensed graphs.
+var ErrUnversioned = errors.New("not a versioned astra file")
+
+// Detect tells a graph from a parsed-records file by its top-level fields.
+func Detect(doc map[string]any) (string, error) {
+	if _, ok := doc["mapped"]; ok {
+		return KindMapped, nil
+	}
+	if _, ok := doc["group_by"]; ok {
+		return "", fmt.Errorf("%w: condensed graphs (astra condense) are derived output", ErrUnversioned)
+	}
+	for _, k := range []string{"artifacts", "steps", "principals", "resources", "edges"} {
+		if _, ok := doc[k]; ok {
+			return KindGraph, nil
+		}
+	}
+	return "", fmt.Errorf("%w: neither an AStRA graph nor parsed records (no artifacts, steps, edges or mapped field)", ErrUnversioned)
+}
+
+// Version returns a document's schema_version; files from before
+// versioning have none and are version 0.
+func Version(doc map[string]any) (int, error) {
+	v, ok := doc["schema_version"]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("schema_version must be an integer, got %s", show(v))
+	}
+	i, err := n.Int64()
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("schema_version must be a non-negative integer, got %s", n)
+	}
+	return int(i), nil
+}
+
+/*
+Upgrade migrates doc in place to the current version of kind and returns
+the version it had and notes on the changes. A document newer than this
+build is an error rather than a guess: upgrade astra instead.
+*/
+func Upgrade(kind string, doc map[string]any) (int, []string, error) {
+	from, err := Version(doc)
+	if err != nil {
+		return 0, nil, err
+	}
+	cur := Current(kind)
+	if from > cur {
+		return from, nil, fmt.Errorf("schema version %d is newer than %d, the latest this astra supports", from, cur)
+	}
+	var notes []string
+	for v := from; v < cur; v++ {
+		found := false
+		for _, m := range migrations[kind] {
+			if m.from == v {
+				notes = append(notes, m.apply(doc)...)
+				found = true
+			}
+		}
+		if !found {
+			return from, nil, fmt.Errorf("no migration for %s from version %d", kind, v)
+		}
+		doc["schema_version"] = json.Number(fmt.Sprint(v + 1))
+	}
+	return from, notes, nil
+}
+
+// graphV1 rewrites step timestamps as RFC 3339 in UTC. Values it cannot
+// read are moved to the step's metadata under "timestamp" so none is lost.
+func graphV1(doc map[string]any) []string {
+	// frozen copy of graph.NormalizeTimestamp as of version 1
+	layouts := []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+		"Mon, _2 Jan 2006 15:04:05 -0700",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02",
+	}
+	normalize := func(s string) (string, bool) {
+		s = strings.TrimSpace(s)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC().Format(time.RFC3339Nano), true
+		}
+		for _, l := range layouts {
+			if t, err := time.Parse(l, s); err == nil {
+				return t.UTC().Format(time.RFC3339Nano), true
+			}
+		}
+		return "", false
+	}
+
+	steps, _ := doc["steps"].([]any)
+	rewritten, moved := 0, 0
+	for _, s := range steps {
+		step, ok := s.(map[string]any)
+		if !ok {
+			continue
+		}
+		ts, _ := step["timestamp"].(string)
+		if ts == "" {
+			continue
+		}
+		if norm, ok := normalize(ts); ok {
+			if norm != ts {
+				step["timestamp"] = norm
+				rewritten++
+			}
+			continue
+		}
+		md, _ := step["metadata"].(map[string]any)
+		if md == nil {
+			md = map[string]any{}
+			step["metadata"] = md
+		}
+		if _, ok := md["timestamp"]; !ok {
+			md["timestamp"] = ts
+		}
+		step["timestamp"] = ""
+		moved++
+	}
+	var notes []string
+	if rewritten > 0 {
+		notes = append(notes, fmt.Sprintf("%d step timestamps rewritten as RFC 3339", rewritten))
+	}
+	if moved > 0 {
+		notes = append(notes, fmt.Sprintf("%d unrecognised step timestamps moved to metadata.timestamp", moved))
+	}
+	return notes
+}
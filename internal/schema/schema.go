@@ -0,0 +1,161 @@
+// Package schema publishes JSON Schema documents for the files astra reads
+// and writes, validates inputs against them and migrates files written by
+// older versions.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	parse "github.com/abuishgair/astra/internal/parser"
+)
+
+//go:generate go run ./gen -o ../../schema
+
+// Kinds of documents; also the base names of the published schema files.
+const (
+	KindGraph  = "astra-graph"
+	KindMapped = "mapped"
+)
+
+const baseID = "https://github.com/abuishgair/astra/schema/"
+
+// Schema is a JSON Schema document or subschema.
+type Schema map[string]any
+
+// Graph is the JSON Schema of the current graph.AstraGraph format.
+func Graph() Schema {
+	return document(KindGraph, "AStRA graph", reflect.TypeOf(graph.AstraGraph{}), graph.SchemaVersion)
+}
+
+// Mapped is the JSON Schema of the current parser.Mapped format.
+func Mapped() Schema {
+	return document(KindMapped, "AStRA parsed records", reflect.TypeOf(parse.Mapped{}), parse.SchemaVersion)
+}
+
+// For returns the schema of a document kind.
+func For(kind string) (Schema, error) {
+	switch kind {
+	case KindGraph:
+		return Graph(), nil
+	case KindMapped:
+		return Mapped(), nil
+	}
+	return nil, fmt.Errorf("unknown document kind %q (%s|%s)", kind, KindGraph, KindMapped)
+}
+
+// Write stores every schema as <dir>/<kind>.v<version>.schema.json and
+// returns the paths written.
+func Write(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, s := range []Schema{Graph(), Mapped()} {
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		p := filepath.Join(dir, filepath.Base(s["$id"].(string)))
+		if err := os.WriteFile(p, append(b, '\n'), 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func document(kind, title string, t reflect.Type, version int) Schema {
+	s := generate(t)
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = fmt.Sprintf("%s%s.v%d.schema.json", baseID, kind, version)
+	s["title"] = fmt.Sprintf("%s, schema version %d", title, version)
+	props := s["properties"].(Schema)
+	props["schema_version"] = Schema{"const": version}
+	return s
+}
+
+/*
+generate derives a schema from a Go type the way encoding/json sees it:
+struct fields by their json tag, fields without omitempty required, nil
+maps and slices allowed as null. A `jsonschema:"format=date-time,empty"`
+tag adds a string format; "empty" also accepts "". Additional properties
+are allowed, so a file with fields from a newer minor change still loads.
+*/
+func generate(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return generate(t.Elem())
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": []any{"array", "null"}, "items": generate(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": []any{"object", "null"}, "additionalProperties": generate(t.Elem())}
+	case reflect.Struct:
+		props := Schema{}
+		var required []any
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fs := generate(f.Type)
+			if tag := f.Tag.Get("jsonschema"); tag != "" {
+				fs = annotate(fs, tag)
+			}
+			props[name] = fs
+			if !strings.Contains(","+opts+",", ",omitempty,") {
+				required = append(required, name)
+			}
+		}
+		s := Schema{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return Schema{}
+}
+
+func annotate(s Schema, tag string) Schema {
+	var format string
+	empty := false
+	for _, opt := range strings.Split(tag, ",") {
+		k, v, _ := strings.Cut(opt, "=")
+		switch k {
+		case "format":
+			format = v
+		case "empty":
+			empty = true
+		}
+	}
+	if format == "" {
+		return s
+	}
+	if !empty {
+		s["format"] = format
+		return s
+	}
+	s["anyOf"] = []any{Schema{"maxLength": 0}, Schema{"format": format}}
+	return s
+}
@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Validate checks a decoded JSON document (as from json.Decoder with
+UseNumber) against s and returns one message per problem, each prefixed
+with the JSON path of the offending value ($.steps[3].timestamp). It covers
+the keywords generate emits: type, properties, required, items,
+additionalProperties, const, maxLength, anyOf and the date-time format.
+*/
+func Validate(s Schema, doc any) []string {
+	var errs []string
+	validate(s, doc, "$", &errs)
+	return errs
+}
+
+func validate(s Schema, v any, path string, errs *[]string) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+	if c, ok := s["const"]; ok && !equal(c, v) {
+		fail("must be %v, got %s", c, show(v))
+		return
+	}
+	if t, ok := s["type"]; ok && !hasType(t, v) {
+		fail("expected %s, got %s", typeNames(t), jsonType(v))
+		return
+	}
+	if alts, ok := s["anyOf"].([]any); ok && len(alts) > 0 {
+		var sub []string
+		for _, a := range alts {
+			sub = nil
+			if validate(subschema(a), v, path, &sub); len(sub) == 0 {
+				break
+			}
+		}
+		// report the last alternative, the informative one (format after "")
+		if len(sub) > 0 {
+			*errs = append(*errs, sub...)
+			return
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		if n, ok := s["maxLength"].(int); ok && len([]rune(x)) > n {
+			fail("longer than %d characters", n)
+		}
+		if s["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, x); err != nil {
+				fail("expected an RFC 3339 date-time, got %q", x)
+			}
+		}
+	case map[string]any:
+		if req, ok := s["required"].([]any); ok {
+			for _, r := range req {
+				if _, ok := x[r.(string)]; !ok {
+					fail("missing required field %q", r)
+				}
+			}
+		}
+		props, _ := s["properties"].(Schema)
+		extra := subschema(s["additionalProperties"])
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k]; ok {
+				validate(subschema(ps), x[k], path+"."+k, errs)
+			} else if extra != nil {
+				validate(extra, x[k], path+"."+k, errs)
+			}
+		}
+	case []any:
+		if items := subschema(s["items"]); items != nil {
+			for i, e := range x {
+				validate(items, e, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+}
+
+func subschema(v any) Schema {
+	switch x := v.(type) {
+	case Schema:
+		return x
+	case map[string]any:
+		return Schema(x)
+	}
+	return nil
+}
+
+func hasType(t, v any) bool {
+	if list, ok := t.([]any); ok {
+		for _, x := range list {
+			if hasType(x, v) {
+				return true
+			}
+		}
+		return false
+	}
+	want, _ := t.(string)
+	got := jsonType(v)
+	return want == got || (want == "number" && got == "integer")
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		var names []string
+		for _, x := range list {
+			names = append(names, fmt.Sprint(x))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func jsonType(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := x.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if x == float64(int64(x)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func equal(c, v any) bool {
+	return fmt.Sprint(c) == show(v)
+}
+
+func show(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,406 @@
+package vuln
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/mapper"
+)
+
+type Advisory struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Severity string   `json:"severity"` // critical|high|medium|low|unimportant|unknown
+	Source   string   `json:"source"`   // osv|debian-tracker
+}
+
+/*
+DB is an offline vulnerability database keyed by package. It is loaded
+from files we supply, never from the network:
+
+  - OSV: one advisory object, an array of them, {"vulns": [...]} (the
+    query API shape), or a directory of such *.json files (an unzipped
+    ecosystem dump).
+  - Debian Security Tracker: the JSON from
+    security-tracker.debian.org/tracker/data/json, keyed by source package.
+
+Debian data is keyed by source package; binary packages only match when
+their name equals the source name or the node carries metadata "source".
+*/
+type DB struct {
+	Files   []string
+	entries map[string][]entry
+}
+
+type entry struct {
+	adv *Advisory
+	// affects reports whether version is vulnerable for the given Debian
+	// release ("" = any) and the version that fixes it, if known.
+	affects func(version, release string) (bool, string)
+}
+
+func NewDB() *DB {
+	return &DB{entries: map[string][]entry{}}
+}
+
+// Load adds a database file or directory to db.
+func (db *DB) Load(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if st.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			if err := db.loadFile(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return db.loadFile(path)
+}
+
+func (db *DB) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return fmt.Errorf("vuln db %s: empty file", path)
+	}
+	db.Files = append(db.Files, path)
+
+	if b[0] == '[' {
+		var list []osvRecord
+		if err := json.Unmarshal(b, &list); err != nil {
+			return fmt.Errorf("vuln db %s: %w", path, err)
+		}
+		db.addOSV(list)
+		return nil
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(b, &top); err != nil {
+		return fmt.Errorf("vuln db %s: %w", path, err)
+	}
+	switch {
+	case top["vulns"] != nil:
+		var w struct {
+			Vulns []osvRecord `json:"vulns"`
+		}
+		if err := json.Unmarshal(b, &w); err != nil {
+			return fmt.Errorf("vuln db %s: %w", path, err)
+		}
+		db.addOSV(w.Vulns)
+	case top["id"] != nil && top["affected"] != nil:
+		var r osvRecord
+		if err := json.Unmarshal(b, &r); err != nil {
+			return fmt.Errorf("vuln db %s: %w", path, err)
+		}
+		db.addOSV([]osvRecord{r})
+	default:
+		var t map[string]map[string]trackerIssue
+		if err := json.Unmarshal(b, &t); err != nil {
+			return fmt.Errorf("vuln db %s: neither OSV nor Debian tracker JSON: %w", path, err)
+		}
+		db.addTracker(t)
+	}
+	return nil
+}
+
+// Lookup returns the advisories affecting pkg (a purl) at its version.
+func (db *DB) Lookup(purl, release string) []Match {
+	id, ok := mapper.ParsePURL(purl)
+	if !ok || id.Version == "" {
+		return nil
+	}
+	var out []Match
+	seen := map[string]bool{}
+	for _, e := range db.entries[packageKey(id.Type, id.Namespace, id.Name)] {
+		if seen[e.adv.ID] {
+			continue
+		}
+		if hit, fixed := e.affects(id.Version, release); hit {
+			seen[e.adv.ID] = true
+			out = append(out, Match{Advisory: *e.adv, Package: purl, Version: id.Version, Fixed: fixed})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// --- OSV ---
+
+type osvRecord struct {
+	ID       string        `json:"id"`
+	Aliases  []string      `json:"aliases"`
+	Summary  string        `json:"summary"`
+	Affected []osvAffected `json:"affected"`
+	DBSpec   struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+		PURL      string `json:"purl"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string              `json:"type"`
+		Events []map[string]string `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+	EcoSpec  struct {
+		Urgency string `json:"urgency"`
+	} `json:"ecosystem_specific"`
+	DBSpec struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// osvEcosystems maps OSV ecosystems to purl type and namespace.
+var osvEcosystems = map[string][2]string{
+	"debian": {"deb", "debian"}, "ubuntu": {"deb", "ubuntu"}, "alpine": {"apk", "alpine"},
+	"npm": {"npm", ""}, "pypi": {"pypi", ""}, "go": {"golang", ""}, "maven": {"maven", ""},
+	"crates.io": {"cargo", ""}, "rubygems": {"gem", ""}, "nuget": {"nuget", ""},
+	"packagist": {"composer", ""}, "hex": {"hex", ""}, "pub": {"pub", ""},
+}
+
+func (db *DB) addOSV(recs []osvRecord) {
+	for _, r := range recs {
+		for _, af := range r.Affected {
+			key := ""
+			if id, ok := mapper.ParsePURL(af.Package.PURL); ok {
+				key = packageKey(id.Type, id.Namespace, id.Name)
+			} else {
+				eco, _, _ := strings.Cut(strings.ToLower(af.Package.Ecosystem), ":")
+				m, ok := osvEcosystems[eco]
+				if !ok || af.Package.Name == "" {
+					continue
+				}
+				key = packageKey(m[0], m[1], af.Package.Name)
+			}
+			adv := &Advisory{
+				ID:       r.ID,
+				Aliases:  r.Aliases,
+				Summary:  r.Summary,
+				Severity: normSeverity(firstOf(af.DBSpec.Severity, af.EcoSpec.Urgency, r.DBSpec.Severity)),
+				Source:   "osv",
+			}
+			af := af
+			db.entries[key] = append(db.entries[key], entry{adv: adv, affects: func(v, _ string) (bool, string) {
+				for _, x := range af.Versions {
+					if CompareVersions(x, v) == 0 {
+						return true, ""
+					}
+				}
+				for _, rg := range af.Ranges {
+					if rg.Type == "GIT" {
+						continue // commit ranges need the repository
+					}
+					if hit, fixed := inRange(v, rg.Events); hit {
+						return true, fixed
+					}
+				}
+				return false, ""
+			}})
+		}
+	}
+}
+
+// inRange pairs each "introduced" event with the next "fixed" or
+// "last_affected" event, in the order OSV lists them.
+func inRange(v string, events []map[string]string) (bool, string) {
+	for i, ev := range events {
+		intro, ok := ev["introduced"]
+		if !ok {
+			continue
+		}
+		if intro != "0" && CompareVersions(v, intro) < 0 {
+			continue
+		}
+		end := true
+		for _, next := range events[i+1:] {
+			if f, ok := next["fixed"]; ok {
+				if CompareVersions(v, f) < 0 {
+					return true, f
+				}
+				end = false
+				break
+			}
+			if l, ok := next["last_affected"]; ok {
+				if CompareVersions(v, l) <= 0 {
+					return true, ""
+				}
+				end = false
+				break
+			}
+			if _, ok := next["introduced"]; ok {
+				break
+			}
+		}
+		if end {
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// --- Debian Security Tracker ---
+
+type trackerIssue struct {
+	Description string `json:"description"`
+	Releases    map[string]struct {
+		Status       string `json:"status"`
+		FixedVersion string `json:"fixed_version"`
+		Urgency      string `json:"urgency"`
+	} `json:"releases"`
+}
+
+var (
+	cveLike = regexp.MustCompile(`^(CVE|DSA|DLA|TEMP)-`)
+	pypiSep = regexp.MustCompile(`[-_.]+`)
+)
+
+/*
+addTracker indexes tracker issues per source package. With a release the
+issue is judged from that release alone. Without one a version is
+affected when any release is still open (it may be the one the package
+came from), or when it is older than the lowest fixed version across
+releases (the fix may be a backport on an older release; anything below
+it is vulnerable everywhere). A fixed_version of "0" means that release
+was never affected; it says nothing about the others and is skipped.
+*/
+func (db *DB) addTracker(t map[string]map[string]trackerIssue) {
+	for pkg, issues := range t {
+		key := packageKey("deb", "debian", pkg)
+		for id, is := range issues {
+			if !cveLike.MatchString(id) {
+				continue
+			}
+			urg := ""
+			for _, rel := range sortedKeys(is.Releases) {
+				if u := is.Releases[rel].Urgency; urg == "" || rank(normSeverity(u)) > rank(normSeverity(urg)) {
+					urg = u
+				}
+			}
+			adv := &Advisory{ID: id, Summary: is.Description, Severity: normSeverity(urg), Source: "debian-tracker"}
+			is := is
+			db.entries[key] = append(db.entries[key], entry{adv: adv, affects: func(v, release string) (bool, string) {
+				if release != "" {
+					r, ok := is.Releases[release]
+					if !ok {
+						return false, ""
+					}
+					switch {
+					case r.Status == "open":
+						return true, ""
+					case r.Status == "resolved" && r.FixedVersion != "0" && CompareVersions(v, r.FixedVersion) < 0:
+						return true, r.FixedVersion
+					}
+					return false, ""
+				}
+				minFixed, open := "", 0
+				for _, rel := range sortedKeys(is.Releases) {
+					r := is.Releases[rel]
+					switch r.Status {
+					case "open":
+						open++
+					case "resolved":
+						if r.FixedVersion == "0" {
+							continue
+						}
+						if minFixed == "" || CompareVersions(r.FixedVersion, minFixed) < 0 {
+							minFixed = r.FixedVersion
+						}
+					}
+				}
+				if open > 0 {
+					return true, ""
+				}
+				if minFixed != "" {
+					return CompareVersions(v, minFixed) < 0, minFixed
+				}
+				return false, ""
+			}})
+		}
+	}
+}
+
+// --- helpers ---
+
+// packageKey identifies a package across purls, OSV and the tracker.
+func packageKey(typ, ns, name string) string {
+	typ = strings.ToLower(typ)
+	switch typ {
+	case "deb", "apk":
+		return typ + "/" + strings.ToLower(ns) + "/" + strings.ToLower(name)
+	case "maven":
+		if ns != "" {
+			return typ + "/" + ns + ":" + name
+		}
+	case "pypi":
+		name = strings.ToLower(pypiSep.ReplaceAllString(name, "-"))
+	}
+	if ns != "" {
+		name = ns + "/" + name
+	}
+	return typ + "/" + name
+}
+
+var severities = []string{"unknown", "unimportant", "low", "medium", "high", "critical"}
+
+func rank(s string) int {
+	for i, v := range severities {
+		if v == s {
+			return i
+		}
+	}
+	return 0
+}
+
+func normSeverity(s string) string {
+	s = strings.ToLower(strings.TrimRight(strings.TrimSpace(s), "*"))
+	switch s {
+	case "moderate":
+		return "medium"
+	case "important":
+		return "high"
+	case "unimportant", "low", "medium", "high", "critical":
+		return s
+	}
+	return "unknown"
+}
+
+func firstOf(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,99 @@
+package vuln
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func load(t *testing.T, content string) *DB {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "db.json")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := NewDB()
+	if err := db.Load(p); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+const tracker = `{
+  "gcc-12": {
+    "CVE-2023-0001": {"releases": {
+      "bullseye": {"status": "resolved", "fixed_version": "0", "urgency": "low"},
+      "bookworm": {"status": "open", "urgency": "medium"}
+    }},
+    "CVE-2023-0002": {"releases": {
+      "bullseye": {"status": "resolved", "fixed_version": "0"},
+      "bookworm": {"status": "resolved", "fixed_version": "0"}
+    }},
+    "CVE-2023-0003": {"releases": {
+      "bookworm": {"status": "resolved", "fixed_version": "12.2.0-14+deb12u1"},
+      "trixie": {"status": "open"}
+    }},
+    "CVE-2023-0004": {"releases": {
+      "bullseye": {"status": "resolved", "fixed_version": "10.2.1-6+deb11u1"},
+      "bookworm": {"status": "resolved", "fixed_version": "12.2.0-14+deb12u1"}
+    }}
+  }
+}`
+
+func TestTracker(t *testing.T) {
+	db := load(t, tracker)
+	for _, tc := range []struct {
+		name, version, release string
+		want                   []string
+	}{
+		// without a release only versions below every fix count for 0004
+		{"any release", "12.2.0-14", "", []string{"CVE-2023-0001", "CVE-2023-0003"}},
+		{"below every fix", "10.2.1-6", "", []string{"CVE-2023-0001", "CVE-2023-0003", "CVE-2023-0004"}},
+		// open in trixie wins over the bookworm fix
+		{"past a fix", "12.2.0-14+deb12u1", "", []string{"CVE-2023-0001", "CVE-2023-0003"}},
+		{"bookworm", "12.2.0-14", "bookworm", []string{"CVE-2023-0001", "CVE-2023-0003", "CVE-2023-0004"}},
+		{"bookworm fixed", "12.2.0-14+deb12u1", "bookworm", []string{"CVE-2023-0001"}},
+		{"bullseye", "10.2.1-6", "bullseye", []string{"CVE-2023-0004"}},
+		{"not in release", "12.2.0-14", "buster", nil},
+	} {
+		var got []string
+		for _, m := range db.Lookup("pkg:deb/debian/gcc-12@"+tc.version, tc.release) {
+			got = append(got, m.ID)
+		}
+		if !equalStrings(got, tc.want) {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOSV(t *testing.T) {
+	db := load(t, `{"vulns": [{"id": "GHSA-1", "affected": [{
+		"package": {"ecosystem": "npm", "name": "left-pad"},
+		"ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.3.0"}, {"introduced": "2.0.0"}, {"last_affected": "2.1.0"}]}],
+		"versions": ["0.9.0"]
+	}]}]}`)
+	for v, want := range map[string]string{
+		"0.9.0": "", "0.9.1": "-", "1.0.0": "1.3.0", "1.2.9": "1.3.0", "1.3.0": "-",
+		"2.0.0": "", "2.1.0": "", "2.1.1": "-",
+	} {
+		ms := db.Lookup("pkg:npm/left-pad@"+v, "")
+		switch {
+		case want == "-" && len(ms) != 0:
+			t.Errorf("%s: unexpected match %+v", v, ms)
+		case want != "-" && (len(ms) != 1 || ms[0].Fixed != want):
+			t.Errorf("%s: %+v, want one match fixed in %q", v, ms, want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package vuln
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/abuishgair/astra/internal/graph"
+	"github.com/abuishgair/astra/internal/mapper"
+	"github.com/abuishgair/astra/internal/query"
+)
+
+type Options struct {
+	// Release restricts Debian tracker data to one release (e.g. "bookworm").
+	Release string
+}
+
+type Match struct {
+	Advisory
+	Node    string `json:"node"`
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Fixed   string `json:"fixed,omitempty"`
+}
+
+// Tainted is an artifact built, directly or transitively, with a
+// vulnerable resource or artifact.
+type Tainted struct {
+	Artifact string   `json:"artifact"`
+	Via      []string `json:"via"`
+}
+
+type Report struct {
+	Databases  []string  `json:"databases"`
+	Checked    int       `json:"checked"` // nodes with a versioned purl
+	Matches    []Match   `json:"matches"`
+	Vulnerable []string  `json:"vulnerable"`
+	Tainted    []Tainted `json:"tainted"`
+}
+
+/*
+Annotate matches every versioned resource and artifact of g against db and
+writes the results into node metadata:
+
+	vulnerabilities      comma-separated advisory IDs
+	vuln_severity        highest severity among them
+	vulnerable_toolchain "true" on artifacts downstream of a vulnerable node
+	vulnerable_inputs    the vulnerable nodes they were built with
+
+Nodes are identified by their purl, else by a purl derived from the ID
+(pkg@version build dependencies, .deb file names). Downstream follows the
+same edges as astra impact.
+*/
+func Annotate(g *graph.AstraGraph, db *DB, opts Options) Report {
+	rep := Report{Databases: db.Files}
+	hits := map[string][]Match{}
+
+	check := func(id, purl string, md map[string]string) {
+		if purl == "" {
+			if ident, ok := mapper.ParseArtifactID(id); ok && ident.Version != "" {
+				purl = ident.PURL()
+			}
+		}
+		ident, ok := mapper.ParsePURL(purl)
+		if !ok || ident.Version == "" {
+			return
+		}
+		rep.Checked++
+		ms := db.Lookup(purl, opts.Release)
+		if src := md["source"]; src != "" && ident.Type == "deb" && src != ident.Name {
+			ident.Name, ident.Qualifiers, ident.Subpath = src, nil, ""
+			ms = append(ms, db.Lookup(ident.PURL(), opts.Release)...)
+		}
+		for i := range ms {
+			ms[i].Node = id
+		}
+		if len(ms) > 0 {
+			hits[id] = ms
+		}
+	}
+	for _, r := range g.Resources {
+		check(r.ID, r.PURL, r.Metadata)
+	}
+	for _, a := range g.Artifacts {
+		check(a.ID, a.PURL, a.Metadata)
+	}
+
+	for _, id := range sortedKeys(hits) {
+		rep.Vulnerable = append(rep.Vulnerable, id)
+		rep.Matches = append(rep.Matches, hits[id]...)
+	}
+	down := query.Downstream(*g, rep.Vulnerable)
+
+	annotate := func(id string, md map[string]string) map[string]string {
+		ms, via := hits[id], down[id]
+		if len(ms) == 0 && len(via) == 0 {
+			return md
+		}
+		if md == nil {
+			md = map[string]string{}
+		}
+		if len(ms) > 0 {
+			var ids []string
+			sev := "unknown"
+			for _, m := range ms {
+				ids = append(ids, m.ID)
+				if rank(m.Severity) > rank(sev) {
+					sev = m.Severity
+				}
+			}
+			md["vulnerabilities"] = strings.Join(dedup(ids), ",")
+			md["vuln_severity"] = sev
+		}
+		return md
+	}
+	for i := range g.Resources {
+		g.Resources[i].Metadata = annotate(g.Resources[i].ID, g.Resources[i].Metadata)
+	}
+	for i := range g.Artifacts {
+		a := &g.Artifacts[i]
+		a.Metadata = annotate(a.ID, a.Metadata)
+		if via := down[a.ID]; len(via) > 0 {
+			a.Metadata["vulnerable_toolchain"] = "true"
+			a.Metadata["vulnerable_inputs"] = strings.Join(via, ",")
+			rep.Tainted = append(rep.Tainted, Tainted{Artifact: a.ID, Via: via})
+		}
+	}
+	sort.Slice(rep.Tainted, func(i, j int) bool { return rep.Tainted[i].Artifact < rep.Tainted[j].Artifact })
+	return rep
+}
+
+func dedup(s []string) []string {
+	sort.Strings(s)
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
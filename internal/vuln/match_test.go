@@ -0,0 +1,50 @@
+package vuln
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abuishgair/astra/internal/parser/buildinfo"
+)
+
+// The main use case: a .deb built with a vulnerable toolchain.
+func TestAnnotateBuildinfo(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "hello.buildinfo")
+	if err := os.WriteFile(p, []byte(`Format: 1.0
+Source: hello
+Version: 2.10-3
+Checksums-Sha256:
+ 5b2d0b7e1c8b0f1e 53204 hello_2.10-3_amd64.deb
+Build-Origin: Debian
+Build-Architecture: amd64
+Installed-Build-Depends:
+ autoconf (= 2.71-3),
+ libc6 (= 2.36-9+deb12u4)
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := buildinfo.ParseGraph(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := load(t, `{"autoconf": {"CVE-2024-0001": {"releases": {
+		"bookworm": {"status": "resolved", "fixed_version": "2.71-3+deb12u1", "urgency": "high"}}}}}`)
+
+	rep := Annotate(g, db, Options{Release: "bookworm"})
+	if len(rep.Vulnerable) != 1 || rep.Vulnerable[0] != "autoconf@2.71-3" {
+		t.Fatalf("vulnerable %v", rep.Vulnerable)
+	}
+	if len(rep.Tainted) != 1 || rep.Tainted[0].Artifact != "hello_2.10-3_amd64.deb" {
+		t.Fatalf("tainted %+v", rep.Tainted)
+	}
+	md := g.Artifacts[0].Metadata
+	if md["vulnerable_toolchain"] != "true" || md["vulnerable_inputs"] != "autoconf@2.71-3" {
+		t.Errorf("deb metadata %v", md)
+	}
+	for _, r := range g.Resources {
+		if r.ID == "autoconf@2.71-3" && (r.Metadata["vulnerabilities"] != "CVE-2024-0001" || r.Metadata["vuln_severity"] != "high") {
+			t.Errorf("autoconf metadata %v", r.Metadata)
+		}
+	}
+}
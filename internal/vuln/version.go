@@ -0,0 +1,109 @@
+package vuln
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+CompareVersions orders two versions with the dpkg algorithm
+([epoch:]upstream[-revision], "~" sorts before everything, letters before
+other symbols). Debian data is compared exactly; other ecosystems are
+approximated the same way, which is right for dotted numeric versions but
+not for every pre-release scheme.
+*/
+func CompareVersions(a, b string) int {
+	ea, ua, ra := splitVersion(a)
+	eb, ub, rb := splitVersion(b)
+	if ea != eb {
+		if ea < eb {
+			return -1
+		}
+		return 1
+	}
+	if c := compareFragment(ua, ub); c != 0 {
+		return c
+	}
+	return compareFragment(ra, rb)
+}
+
+func splitVersion(v string) (epoch int, upstream, revision string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(v[:i])
+		v = v[i+1:]
+	}
+	upstream = v
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		upstream, revision = v[:i], v[i+1:]
+	}
+	return epoch, upstream, revision
+}
+
+// compareFragment alternates non-digit and digit runs, as dpkg does.
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		var na, nb string
+		na, a = span(a, false)
+		nb, b = span(b, false)
+		if c := compareLexical(na, nb); c != 0 {
+			return c
+		}
+		na, a = span(a, true)
+		nb, b = span(b, true)
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func span(s string, digits bool) (run, rest string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func order(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareLexical(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var oa, ob int
+		if i < len(a) {
+			oa = order(a[i])
+		}
+		if i < len(b) {
+			ob = order(b[i])
+		}
+		if oa != ob {
+			if oa < ob {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,31 @@
+package vuln
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0-1", "1.0-2", -1},
+		{"1:0.9", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1-1", "1.0~rc2-1", -1},
+		{"1.0a", "1.0+", -1},
+		{"1.0", "1.0.1", -1},
+		{"12.2.0-14", "12.2.0-14+deb12u1", -1},
+		{"2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		{"007", "7", 0},
+		{"v1.2.3", "1.2.3", 0},
+	} {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := CompareVersions(tc.b, tc.a); got != -tc.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.b, tc.a, got, -tc.want)
+		}
+	}
+}